@@ -4,6 +4,8 @@ go 1.13
 
 require (
 	github.com/diamondburned/arikawa/v2 v2.0.2
+	github.com/gorilla/websocket v1.4.2
 	github.com/mavolin/dismock/v2 v2.0.0
 	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.7.0
 )
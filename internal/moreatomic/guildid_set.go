@@ -49,3 +49,18 @@ func (s *GuildIDSet) Delete(flake discord.GuildID) bool {
 
 	return false
 }
+
+// Clear removes all elements from the set.
+func (s *GuildIDSet) Clear() {
+	s.mut.Lock()
+	s.set = make(map[discord.GuildID]struct{})
+	s.mut.Unlock()
+}
+
+// Len returns the number of elements in the set.
+func (s *GuildIDSet) Len() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return len(s.set)
+}
@@ -0,0 +1,482 @@
+package cabinet
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/pkg/errors"
+)
+
+// Resource is a resource stored in a Cabinet.
+type Resource string
+
+// The resources of a Cabinet.
+const (
+	ResourceMe         Resource = "me"
+	ResourceChannel    Resource = "channel"
+	ResourceEmoji      Resource = "emoji"
+	ResourceGuild      Resource = "guild"
+	ResourceMember     Resource = "member"
+	ResourceMessage    Resource = "message"
+	ResourcePresence   Resource = "presence"
+	ResourceRole       Resource = "role"
+	ResourceVoiceState Resource = "voice_state"
+)
+
+// resources are all Resources in the order of the stores of a
+// store.Cabinet.
+var resources = []Resource{
+	ResourceMe,
+	ResourceChannel,
+	ResourceEmoji,
+	ResourceGuild,
+	ResourceMember,
+	ResourceMessage,
+	ResourcePresence,
+	ResourceRole,
+	ResourceVoiceState,
+}
+
+type (
+	// Instrumentation records statistics about the usage of a Cabinet
+	// created using Instrument.
+	Instrumentation struct {
+		counters map[Resource]*counters
+	}
+
+	// ResourceStats are the statistics of a single Resource.
+	ResourceStats struct {
+		// Hits is the number of reads that found the requested data.
+		Hits uint64
+		// Misses is the number of reads that returned store.ErrNotFound.
+		Misses uint64
+		// Sets is the number of successful writes.
+		Sets uint64
+		// Removes is the number of successful removals.
+		Removes uint64
+		// Errors is the number of operations that failed with an error
+		// other than store.ErrNotFound.
+		Errors uint64
+
+		// ReadTime is the total time spent reading.
+		ReadTime time.Duration
+		// WriteTime is the total time spent writing and removing.
+		WriteTime time.Duration
+	}
+
+	counters struct {
+		hits, misses, sets, removes, errors uint64
+		readTime, writeTime                 int64
+	}
+)
+
+// Instrument wraps all stores of the passed store.Cabinet, so that their usage
+// is recorded by the returned Instrumentation.
+func Instrument(cab store.Cabinet) (store.Cabinet, *Instrumentation) {
+	i := &Instrumentation{counters: make(map[Resource]*counters, len(resources))}
+
+	for _, r := range resources {
+		i.counters[r] = new(counters)
+	}
+
+	cab.MeStore = &instrumentedMe{MeStore: cab.MeStore, c: i.counters[ResourceMe]}
+	cab.ChannelStore = &instrumentedChannel{ChannelStore: cab.ChannelStore, c: i.counters[ResourceChannel]}
+	cab.EmojiStore = &instrumentedEmoji{EmojiStore: cab.EmojiStore, c: i.counters[ResourceEmoji]}
+	cab.GuildStore = &instrumentedGuild{GuildStore: cab.GuildStore, c: i.counters[ResourceGuild]}
+	cab.MemberStore = &instrumentedMember{MemberStore: cab.MemberStore, c: i.counters[ResourceMember]}
+	cab.MessageStore = &instrumentedMessage{MessageStore: cab.MessageStore, c: i.counters[ResourceMessage]}
+	cab.PresenceStore = &instrumentedPresence{PresenceStore: cab.PresenceStore, c: i.counters[ResourcePresence]}
+	cab.RoleStore = &instrumentedRole{RoleStore: cab.RoleStore, c: i.counters[ResourceRole]}
+	cab.VoiceStateStore = &instrumentedVoiceState{VoiceStateStore: cab.VoiceStateStore, c: i.counters[ResourceVoiceState]}
+
+	return cab, i
+}
+
+// Stats returns the statistics of all Resources.
+func (i *Instrumentation) Stats() map[Resource]ResourceStats {
+	stats := make(map[Resource]ResourceStats, len(i.counters))
+
+	for r, c := range i.counters {
+		stats[r] = ResourceStats{
+			Hits:      atomic.LoadUint64(&c.hits),
+			Misses:    atomic.LoadUint64(&c.misses),
+			Sets:      atomic.LoadUint64(&c.sets),
+			Removes:   atomic.LoadUint64(&c.removes),
+			Errors:    atomic.LoadUint64(&c.errors),
+			ReadTime:  time.Duration(atomic.LoadInt64(&c.readTime)),
+			WriteTime: time.Duration(atomic.LoadInt64(&c.writeTime)),
+		}
+	}
+
+	return stats
+}
+
+// Reset resets all statistics.
+func (i *Instrumentation) Reset() {
+	for _, c := range i.counters {
+		atomic.StoreUint64(&c.hits, 0)
+		atomic.StoreUint64(&c.misses, 0)
+		atomic.StoreUint64(&c.sets, 0)
+		atomic.StoreUint64(&c.removes, 0)
+		atomic.StoreUint64(&c.errors, 0)
+		atomic.StoreInt64(&c.readTime, 0)
+		atomic.StoreInt64(&c.writeTime, 0)
+	}
+}
+
+// read records a read that started at start and returned err.
+func (c *counters) read(start time.Time, err error) {
+	atomic.AddInt64(&c.readTime, int64(time.Since(start)))
+
+	switch {
+	case err == nil:
+		atomic.AddUint64(&c.hits, 1)
+	case errors.Is(err, store.ErrNotFound):
+		atomic.AddUint64(&c.misses, 1)
+	default:
+		atomic.AddUint64(&c.errors, 1)
+	}
+}
+
+// write records a write that started at start and returned err.
+// If remove is true, the write was a removal.
+func (c *counters) write(start time.Time, err error, remove bool) {
+	atomic.AddInt64(&c.writeTime, int64(time.Since(start)))
+
+	switch {
+	case err != nil:
+		atomic.AddUint64(&c.errors, 1)
+	case remove:
+		atomic.AddUint64(&c.removes, 1)
+	default:
+		atomic.AddUint64(&c.sets, 1)
+	}
+}
+
+type instrumentedMe struct {
+	store.MeStore
+	c *counters
+}
+
+func (s *instrumentedMe) Me() (*discord.User, error) {
+	start := time.Now()
+	v, err := s.MeStore.Me()
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedMe) MyselfSet(me discord.User) error {
+	start := time.Now()
+	err := s.MeStore.MyselfSet(me)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+type instrumentedChannel struct {
+	store.ChannelStore
+	c *counters
+}
+
+func (s *instrumentedChannel) Channel(id discord.ChannelID) (*discord.Channel, error) {
+	start := time.Now()
+	v, err := s.ChannelStore.Channel(id)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedChannel) CreatePrivateChannel(recipient discord.UserID) (*discord.Channel, error) {
+	start := time.Now()
+	v, err := s.ChannelStore.CreatePrivateChannel(recipient)
+	s.c.write(start, err, false)
+
+	return v, err
+}
+
+func (s *instrumentedChannel) Channels(guildID discord.GuildID) ([]discord.Channel, error) {
+	start := time.Now()
+	v, err := s.ChannelStore.Channels(guildID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedChannel) PrivateChannels() ([]discord.Channel, error) {
+	start := time.Now()
+	v, err := s.ChannelStore.PrivateChannels()
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedChannel) ChannelSet(c discord.Channel) error {
+	start := time.Now()
+	err := s.ChannelStore.ChannelSet(c)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+func (s *instrumentedChannel) ChannelRemove(c discord.Channel) error {
+	start := time.Now()
+	err := s.ChannelStore.ChannelRemove(c)
+	s.c.write(start, err, true)
+
+	return err
+}
+
+type instrumentedEmoji struct {
+	store.EmojiStore
+	c *counters
+}
+
+func (s *instrumentedEmoji) Emoji(guildID discord.GuildID, emojiID discord.EmojiID) (*discord.Emoji, error) {
+	start := time.Now()
+	v, err := s.EmojiStore.Emoji(guildID, emojiID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedEmoji) Emojis(guildID discord.GuildID) ([]discord.Emoji, error) {
+	start := time.Now()
+	v, err := s.EmojiStore.Emojis(guildID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedEmoji) EmojiSet(guildID discord.GuildID, emojis []discord.Emoji) error {
+	start := time.Now()
+	err := s.EmojiStore.EmojiSet(guildID, emojis)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+type instrumentedGuild struct {
+	store.GuildStore
+	c *counters
+}
+
+func (s *instrumentedGuild) Guild(id discord.GuildID) (*discord.Guild, error) {
+	start := time.Now()
+	v, err := s.GuildStore.Guild(id)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedGuild) Guilds() ([]discord.Guild, error) {
+	start := time.Now()
+	v, err := s.GuildStore.Guilds()
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedGuild) GuildSet(g discord.Guild) error {
+	start := time.Now()
+	err := s.GuildStore.GuildSet(g)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+func (s *instrumentedGuild) GuildRemove(id discord.GuildID) error {
+	start := time.Now()
+	err := s.GuildStore.GuildRemove(id)
+	s.c.write(start, err, true)
+
+	return err
+}
+
+type instrumentedMember struct {
+	store.MemberStore
+	c *counters
+}
+
+func (s *instrumentedMember) Member(guildID discord.GuildID, userID discord.UserID) (*discord.Member, error) {
+	start := time.Now()
+	v, err := s.MemberStore.Member(guildID, userID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedMember) Members(guildID discord.GuildID) ([]discord.Member, error) {
+	start := time.Now()
+	v, err := s.MemberStore.Members(guildID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedMember) MemberSet(guildID discord.GuildID, m discord.Member) error {
+	start := time.Now()
+	err := s.MemberStore.MemberSet(guildID, m)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+func (s *instrumentedMember) MemberRemove(guildID discord.GuildID, userID discord.UserID) error {
+	start := time.Now()
+	err := s.MemberStore.MemberRemove(guildID, userID)
+	s.c.write(start, err, true)
+
+	return err
+}
+
+type instrumentedMessage struct {
+	store.MessageStore
+	c *counters
+}
+
+func (s *instrumentedMessage) Message(channelID discord.ChannelID, messageID discord.MessageID) (*discord.Message, error) {
+	start := time.Now()
+	v, err := s.MessageStore.Message(channelID, messageID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedMessage) Messages(channelID discord.ChannelID) ([]discord.Message, error) {
+	start := time.Now()
+	v, err := s.MessageStore.Messages(channelID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedMessage) MessageSet(m discord.Message) error {
+	start := time.Now()
+	err := s.MessageStore.MessageSet(m)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+func (s *instrumentedMessage) MessageRemove(channelID discord.ChannelID, messageID discord.MessageID) error {
+	start := time.Now()
+	err := s.MessageStore.MessageRemove(channelID, messageID)
+	s.c.write(start, err, true)
+
+	return err
+}
+
+type instrumentedPresence struct {
+	store.PresenceStore
+	c *counters
+}
+
+func (s *instrumentedPresence) Presence(guildID discord.GuildID, userID discord.UserID) (*gateway.Presence, error) {
+	start := time.Now()
+	v, err := s.PresenceStore.Presence(guildID, userID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedPresence) Presences(guildID discord.GuildID) ([]gateway.Presence, error) {
+	start := time.Now()
+	v, err := s.PresenceStore.Presences(guildID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedPresence) PresenceSet(guildID discord.GuildID, p gateway.Presence) error {
+	start := time.Now()
+	err := s.PresenceStore.PresenceSet(guildID, p)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+func (s *instrumentedPresence) PresenceRemove(guildID discord.GuildID, userID discord.UserID) error {
+	start := time.Now()
+	err := s.PresenceStore.PresenceRemove(guildID, userID)
+	s.c.write(start, err, true)
+
+	return err
+}
+
+type instrumentedRole struct {
+	store.RoleStore
+	c *counters
+}
+
+func (s *instrumentedRole) Role(guildID discord.GuildID, roleID discord.RoleID) (*discord.Role, error) {
+	start := time.Now()
+	v, err := s.RoleStore.Role(guildID, roleID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedRole) Roles(guildID discord.GuildID) ([]discord.Role, error) {
+	start := time.Now()
+	v, err := s.RoleStore.Roles(guildID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedRole) RoleSet(guildID discord.GuildID, r discord.Role) error {
+	start := time.Now()
+	err := s.RoleStore.RoleSet(guildID, r)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+func (s *instrumentedRole) RoleRemove(guildID discord.GuildID, roleID discord.RoleID) error {
+	start := time.Now()
+	err := s.RoleStore.RoleRemove(guildID, roleID)
+	s.c.write(start, err, true)
+
+	return err
+}
+
+type instrumentedVoiceState struct {
+	store.VoiceStateStore
+	c *counters
+}
+
+func (s *instrumentedVoiceState) VoiceState(guildID discord.GuildID, userID discord.UserID) (*discord.VoiceState, error) {
+	start := time.Now()
+	v, err := s.VoiceStateStore.VoiceState(guildID, userID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedVoiceState) VoiceStates(guildID discord.GuildID) ([]discord.VoiceState, error) {
+	start := time.Now()
+	v, err := s.VoiceStateStore.VoiceStates(guildID)
+	s.c.read(start, err)
+
+	return v, err
+}
+
+func (s *instrumentedVoiceState) VoiceStateSet(guildID discord.GuildID, vs discord.VoiceState) error {
+	start := time.Now()
+	err := s.VoiceStateStore.VoiceStateSet(guildID, vs)
+	s.c.write(start, err, false)
+
+	return err
+}
+
+func (s *instrumentedVoiceState) VoiceStateRemove(guildID discord.GuildID, userID discord.UserID) error {
+	start := time.Now()
+	err := s.VoiceStateStore.VoiceStateRemove(guildID, userID)
+	s.c.write(start, err, true)
+
+	return err
+}
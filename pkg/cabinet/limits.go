@@ -0,0 +1,254 @@
+package cabinet
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/state/store"
+)
+
+// Limits are the limits enforced by a Cabinet created using Limit.
+// A limit of 0 means no limit.
+type Limits struct {
+	// MembersPerGuild is the maximum number of members cached per guild.
+	MembersPerGuild int
+	// PresencesPerGuild is the maximum number of presences cached per
+	// guild.
+	PresencesPerGuild int
+	// MessageChannels is the maximum number of channels whose messages are
+	// cached.
+	// The number of messages per channel is limited by the MessageStore
+	// itself.
+	MessageChannels int
+}
+
+// Limit wraps the stores of the passed store.Cabinet, so that they enforce
+// the passed Limits.
+// If a limit is exceeded, the least recently used entry is removed.
+//
+// Only entries set through the returned Cabinet are tracked, therefore, the
+// wrapped stores should not be modified directly.
+func Limit(cab store.Cabinet, l Limits) store.Cabinet {
+	if l.MembersPerGuild > 0 {
+		cab.MemberStore = &limitedMember{
+			MemberStore: cab.MemberStore,
+			max:         l.MembersPerGuild,
+			guilds:      make(map[discord.GuildID]*lru),
+		}
+	}
+
+	if l.PresencesPerGuild > 0 {
+		cab.PresenceStore = &limitedPresence{
+			PresenceStore: cab.PresenceStore,
+			max:           l.PresencesPerGuild,
+			guilds:        make(map[discord.GuildID]*lru),
+		}
+	}
+
+	if l.MessageChannels > 0 {
+		cab.MessageStore = &limitedMessage{
+			MessageStore: cab.MessageStore,
+			channels:     newLRU(l.MessageChannels),
+		}
+	}
+
+	return cab
+}
+
+type limitedMember struct {
+	store.MemberStore
+	max int
+
+	guilds map[discord.GuildID]*lru
+	mutex  sync.Mutex
+}
+
+func (s *limitedMember) Reset() error {
+	s.mutex.Lock()
+	s.guilds = make(map[discord.GuildID]*lru)
+	s.mutex.Unlock()
+
+	return s.MemberStore.Reset()
+}
+
+func (s *limitedMember) Member(guildID discord.GuildID, userID discord.UserID) (*discord.Member, error) {
+	m, err := s.MemberStore.Member(guildID, userID)
+	if err == nil {
+		s.mutex.Lock()
+		if l, ok := s.guilds[guildID]; ok {
+			l.use(userID)
+		}
+		s.mutex.Unlock()
+	}
+
+	return m, err
+}
+
+func (s *limitedMember) MemberSet(guildID discord.GuildID, m discord.Member) error {
+	if err := s.MemberStore.MemberSet(guildID, m); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	l, ok := s.guilds[guildID]
+	if !ok {
+		l = newLRU(s.max)
+		s.guilds[guildID] = l
+	}
+
+	if evicted, ok := l.touch(m.User.ID); ok {
+		return s.MemberStore.MemberRemove(guildID, evicted.(discord.UserID))
+	}
+
+	return nil
+}
+
+func (s *limitedMember) MemberRemove(guildID discord.GuildID, userID discord.UserID) error {
+	s.mutex.Lock()
+	if l, ok := s.guilds[guildID]; ok {
+		l.remove(userID)
+
+		if l.len() == 0 {
+			delete(s.guilds, guildID)
+		}
+	}
+	s.mutex.Unlock()
+
+	return s.MemberStore.MemberRemove(guildID, userID)
+}
+
+type limitedPresence struct {
+	store.PresenceStore
+	max int
+
+	guilds map[discord.GuildID]*lru
+	mutex  sync.Mutex
+}
+
+func (s *limitedPresence) Reset() error {
+	s.mutex.Lock()
+	s.guilds = make(map[discord.GuildID]*lru)
+	s.mutex.Unlock()
+
+	return s.PresenceStore.Reset()
+}
+
+func (s *limitedPresence) Presence(guildID discord.GuildID, userID discord.UserID) (*gateway.Presence, error) {
+	p, err := s.PresenceStore.Presence(guildID, userID)
+	if err == nil {
+		s.mutex.Lock()
+		if l, ok := s.guilds[guildID]; ok {
+			l.use(userID)
+		}
+		s.mutex.Unlock()
+	}
+
+	return p, err
+}
+
+func (s *limitedPresence) PresenceSet(guildID discord.GuildID, p gateway.Presence) error {
+	if err := s.PresenceStore.PresenceSet(guildID, p); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	l, ok := s.guilds[guildID]
+	if !ok {
+		l = newLRU(s.max)
+		s.guilds[guildID] = l
+	}
+
+	if evicted, ok := l.touch(p.User.ID); ok {
+		return s.PresenceStore.PresenceRemove(guildID, evicted.(discord.UserID))
+	}
+
+	return nil
+}
+
+func (s *limitedPresence) PresenceRemove(guildID discord.GuildID, userID discord.UserID) error {
+	s.mutex.Lock()
+	if l, ok := s.guilds[guildID]; ok {
+		l.remove(userID)
+
+		if l.len() == 0 {
+			delete(s.guilds, guildID)
+		}
+	}
+	s.mutex.Unlock()
+
+	return s.PresenceStore.PresenceRemove(guildID, userID)
+}
+
+type limitedMessage struct {
+	store.MessageStore
+
+	channels *lru
+	mutex    sync.Mutex
+}
+
+func (s *limitedMessage) Reset() error {
+	s.mutex.Lock()
+	s.channels = newLRU(s.channels.max)
+	s.mutex.Unlock()
+
+	return s.MessageStore.Reset()
+}
+
+func (s *limitedMessage) Message(channelID discord.ChannelID, messageID discord.MessageID) (*discord.Message, error) {
+	m, err := s.MessageStore.Message(channelID, messageID)
+	if err == nil {
+		s.mutex.Lock()
+		s.channels.use(channelID)
+		s.mutex.Unlock()
+	}
+
+	return m, err
+}
+
+func (s *limitedMessage) Messages(channelID discord.ChannelID) ([]discord.Message, error) {
+	msgs, err := s.MessageStore.Messages(channelID)
+	if err == nil {
+		s.mutex.Lock()
+		s.channels.use(channelID)
+		s.mutex.Unlock()
+	}
+
+	return msgs, err
+}
+
+func (s *limitedMessage) MessageSet(m discord.Message) error {
+	if err := s.MessageStore.MessageSet(m); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	evicted, ok := s.channels.touch(m.ChannelID)
+	if !ok {
+		return nil
+	}
+
+	channelID := evicted.(discord.ChannelID)
+
+	msgs, err := s.MessageStore.Messages(channelID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, m := range msgs {
+		if err := s.MessageStore.MessageRemove(channelID, m.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package cabinet
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU_touch(t *testing.T) {
+	l := newLRU(2)
+
+	_, ok := l.touch(1)
+	assert.False(t, ok)
+
+	_, ok = l.touch(2)
+	assert.False(t, ok)
+
+	// 1 is now used more recently than 2
+	l.use(1)
+
+	evicted, ok := l.touch(3)
+	assert.True(t, ok)
+	assert.Equal(t, 2, evicted)
+
+	// touching a tracked key doesn't evict
+	_, ok = l.touch(1)
+	assert.False(t, ok)
+
+	evicted, ok = l.touch(4)
+	assert.True(t, ok)
+	assert.Equal(t, 3, evicted)
+
+	l.remove(1)
+	assert.Equal(t, 1, l.len())
+}
+
+func TestLimit(t *testing.T) {
+	t.Run("members", func(t *testing.T) {
+		cab := Limit(defaultstore.New(), Limits{MembersPerGuild: 2})
+
+		for id := discord.UserID(1); id <= 2; id++ {
+			require.NoError(t, cab.MemberSet(1, discord.Member{User: discord.User{ID: id}}))
+		}
+
+		// use 1, so that 2 is evicted instead
+		_, err := cab.Member(1, 1)
+		require.NoError(t, err)
+
+		require.NoError(t, cab.MemberSet(1, discord.Member{User: discord.User{ID: 3}}))
+
+		_, err = cab.Member(1, 2)
+		assert.Equal(t, store.ErrNotFound, err)
+
+		_, err = cab.Member(1, 1)
+		assert.NoError(t, err)
+
+		_, err = cab.Member(1, 3)
+		assert.NoError(t, err)
+
+		// limits are per guild
+		require.NoError(t, cab.MemberSet(2, discord.Member{User: discord.User{ID: 4}}))
+
+		_, err = cab.Member(1, 1)
+		assert.NoError(t, err)
+	})
+
+	t.Run("message channels", func(t *testing.T) {
+		cab := Limit(defaultstore.New(), Limits{MessageChannels: 1})
+
+		require.NoError(t, cab.MessageSet(discord.Message{ID: 1, ChannelID: 1}))
+		require.NoError(t, cab.MessageSet(discord.Message{ID: 2, ChannelID: 2}))
+
+		// the messages of the least recently used channel are removed
+		_, err := cab.Message(1, 1)
+		assert.Equal(t, store.ErrNotFound, err)
+
+		_, err = cab.Message(2, 2)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,61 @@
+package cabinet
+
+import "container/list"
+
+// lru keeps track of the usage order of keys.
+// It is not safe for concurrent use.
+type lru struct {
+	max   int
+	order *list.List
+	elems map[interface{}]*list.Element
+}
+
+func newLRU(max int) *lru {
+	return &lru{
+		max:   max,
+		order: list.New(),
+		elems: make(map[interface{}]*list.Element),
+	}
+}
+
+// touch marks the passed key as most recently used, adding it if necessary.
+// If adding the key exceeds the maximum, the least recently used key is
+// removed and returned.
+func (l *lru) touch(key interface{}) (evicted interface{}, ok bool) {
+	if e, ok := l.elems[key]; ok {
+		l.order.MoveToFront(e)
+		return nil, false
+	}
+
+	l.elems[key] = l.order.PushFront(key)
+
+	if l.order.Len() <= l.max {
+		return nil, false
+	}
+
+	back := l.order.Back()
+	l.order.Remove(back)
+	delete(l.elems, back.Value)
+
+	return back.Value, true
+}
+
+// use marks the passed key as most recently used, if it is tracked.
+func (l *lru) use(key interface{}) {
+	if e, ok := l.elems[key]; ok {
+		l.order.MoveToFront(e)
+	}
+}
+
+// remove stops tracking the passed key.
+func (l *lru) remove(key interface{}) {
+	if e, ok := l.elems[key]; ok {
+		l.order.Remove(e)
+		delete(l.elems, key)
+	}
+}
+
+// len returns the number of tracked keys.
+func (l *lru) len() int {
+	return l.order.Len()
+}
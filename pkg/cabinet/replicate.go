@@ -0,0 +1,204 @@
+package cabinet
+
+import (
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/pkg/errors"
+)
+
+// Replicate returns a store.Cabinet that reads from primary, and mirrors all
+// writes, removals and resets to secondary, e.g. to keep the cache of a
+// standby process warm.
+//
+// A write is only mirrored, if it succeeded on primary.
+// Errors returned by secondary are passed to onError, if it is not nil, and
+// are otherwise discarded, so that a broken secondary never affects primary.
+func Replicate(primary, secondary store.Cabinet, onError func(error)) store.Cabinet {
+	r := &replicator{onError: onError}
+
+	primary.MeStore = &replicatedMe{MeStore: primary.MeStore, secondary: secondary.MeStore, r: r}
+	primary.ChannelStore = &replicatedChannel{ChannelStore: primary.ChannelStore, secondary: secondary.ChannelStore, r: r}
+	primary.EmojiStore = &replicatedEmoji{EmojiStore: primary.EmojiStore, secondary: secondary.EmojiStore, r: r}
+	primary.GuildStore = &replicatedGuild{GuildStore: primary.GuildStore, secondary: secondary.GuildStore, r: r}
+	primary.MemberStore = &replicatedMember{MemberStore: primary.MemberStore, secondary: secondary.MemberStore, r: r}
+	primary.MessageStore = &replicatedMessage{MessageStore: primary.MessageStore, secondary: secondary.MessageStore, r: r}
+	primary.PresenceStore = &replicatedPresence{PresenceStore: primary.PresenceStore, secondary: secondary.PresenceStore, r: r}
+	primary.RoleStore = &replicatedRole{RoleStore: primary.RoleStore, secondary: secondary.RoleStore, r: r}
+	primary.VoiceStateStore = &replicatedVoiceState{VoiceStateStore: primary.VoiceStateStore, secondary: secondary.VoiceStateStore, r: r}
+
+	return primary
+}
+
+type replicator struct {
+	onError func(error)
+}
+
+// mirror calls write, if err is nil, and reports errors returned by write to
+// the onError function of the replicator.
+// It returns err.
+func (r *replicator) mirror(err error, write func() error) error {
+	if err != nil {
+		return err
+	}
+
+	if err := write(); err != nil && r.onError != nil {
+		r.onError(errors.Wrap(err, "failed to replicate write"))
+	}
+
+	return nil
+}
+
+type replicatedMe struct {
+	store.MeStore
+	secondary store.MeStore
+	r         *replicator
+}
+
+func (s *replicatedMe) Reset() error {
+	return s.r.mirror(s.MeStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedMe) MyselfSet(me discord.User) error {
+	return s.r.mirror(s.MeStore.MyselfSet(me), func() error { return s.secondary.MyselfSet(me) })
+}
+
+type replicatedChannel struct {
+	store.ChannelStore
+	secondary store.ChannelStore
+	r         *replicator
+}
+
+func (s *replicatedChannel) Reset() error {
+	return s.r.mirror(s.ChannelStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedChannel) ChannelSet(c discord.Channel) error {
+	return s.r.mirror(s.ChannelStore.ChannelSet(c), func() error { return s.secondary.ChannelSet(c) })
+}
+
+func (s *replicatedChannel) ChannelRemove(c discord.Channel) error {
+	return s.r.mirror(s.ChannelStore.ChannelRemove(c), func() error { return s.secondary.ChannelRemove(c) })
+}
+
+type replicatedEmoji struct {
+	store.EmojiStore
+	secondary store.EmojiStore
+	r         *replicator
+}
+
+func (s *replicatedEmoji) Reset() error {
+	return s.r.mirror(s.EmojiStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedEmoji) EmojiSet(guildID discord.GuildID, emojis []discord.Emoji) error {
+	return s.r.mirror(s.EmojiStore.EmojiSet(guildID, emojis), func() error { return s.secondary.EmojiSet(guildID, emojis) })
+}
+
+type replicatedGuild struct {
+	store.GuildStore
+	secondary store.GuildStore
+	r         *replicator
+}
+
+func (s *replicatedGuild) Reset() error {
+	return s.r.mirror(s.GuildStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedGuild) GuildSet(g discord.Guild) error {
+	return s.r.mirror(s.GuildStore.GuildSet(g), func() error { return s.secondary.GuildSet(g) })
+}
+
+func (s *replicatedGuild) GuildRemove(id discord.GuildID) error {
+	return s.r.mirror(s.GuildStore.GuildRemove(id), func() error { return s.secondary.GuildRemove(id) })
+}
+
+type replicatedMember struct {
+	store.MemberStore
+	secondary store.MemberStore
+	r         *replicator
+}
+
+func (s *replicatedMember) Reset() error {
+	return s.r.mirror(s.MemberStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedMember) MemberSet(guildID discord.GuildID, m discord.Member) error {
+	return s.r.mirror(s.MemberStore.MemberSet(guildID, m), func() error { return s.secondary.MemberSet(guildID, m) })
+}
+
+func (s *replicatedMember) MemberRemove(guildID discord.GuildID, userID discord.UserID) error {
+	return s.r.mirror(s.MemberStore.MemberRemove(guildID, userID), func() error { return s.secondary.MemberRemove(guildID, userID) })
+}
+
+type replicatedMessage struct {
+	store.MessageStore
+	secondary store.MessageStore
+	r         *replicator
+}
+
+func (s *replicatedMessage) Reset() error {
+	return s.r.mirror(s.MessageStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedMessage) MessageSet(m discord.Message) error {
+	return s.r.mirror(s.MessageStore.MessageSet(m), func() error { return s.secondary.MessageSet(m) })
+}
+
+func (s *replicatedMessage) MessageRemove(channelID discord.ChannelID, messageID discord.MessageID) error {
+	return s.r.mirror(s.MessageStore.MessageRemove(channelID, messageID), func() error { return s.secondary.MessageRemove(channelID, messageID) })
+}
+
+type replicatedPresence struct {
+	store.PresenceStore
+	secondary store.PresenceStore
+	r         *replicator
+}
+
+func (s *replicatedPresence) Reset() error {
+	return s.r.mirror(s.PresenceStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedPresence) PresenceSet(guildID discord.GuildID, p gateway.Presence) error {
+	return s.r.mirror(s.PresenceStore.PresenceSet(guildID, p), func() error { return s.secondary.PresenceSet(guildID, p) })
+}
+
+func (s *replicatedPresence) PresenceRemove(guildID discord.GuildID, userID discord.UserID) error {
+	return s.r.mirror(s.PresenceStore.PresenceRemove(guildID, userID), func() error { return s.secondary.PresenceRemove(guildID, userID) })
+}
+
+type replicatedRole struct {
+	store.RoleStore
+	secondary store.RoleStore
+	r         *replicator
+}
+
+func (s *replicatedRole) Reset() error {
+	return s.r.mirror(s.RoleStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedRole) RoleSet(guildID discord.GuildID, r discord.Role) error {
+	return s.r.mirror(s.RoleStore.RoleSet(guildID, r), func() error { return s.secondary.RoleSet(guildID, r) })
+}
+
+func (s *replicatedRole) RoleRemove(guildID discord.GuildID, roleID discord.RoleID) error {
+	return s.r.mirror(s.RoleStore.RoleRemove(guildID, roleID), func() error { return s.secondary.RoleRemove(guildID, roleID) })
+}
+
+type replicatedVoiceState struct {
+	store.VoiceStateStore
+	secondary store.VoiceStateStore
+	r         *replicator
+}
+
+func (s *replicatedVoiceState) Reset() error {
+	return s.r.mirror(s.VoiceStateStore.Reset(), s.secondary.Reset)
+}
+
+func (s *replicatedVoiceState) VoiceStateSet(guildID discord.GuildID, vs discord.VoiceState) error {
+	return s.r.mirror(s.VoiceStateStore.VoiceStateSet(guildID, vs), func() error { return s.secondary.VoiceStateSet(guildID, vs) })
+}
+
+func (s *replicatedVoiceState) VoiceStateRemove(guildID discord.GuildID, userID discord.UserID) error {
+	return s.r.mirror(s.VoiceStateStore.VoiceStateRemove(guildID, userID), func() error { return s.secondary.VoiceStateRemove(guildID, userID) })
+}
@@ -0,0 +1,218 @@
+package cabinet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
+)
+
+// TTLMessage is a store.MessageStore that keeps messages for a fixed
+// duration, in addition to limiting the number of messages per channel.
+//
+// Expired messages are removed when accessing their channel, and by a janitor
+// that periodically sweeps all channels.
+// The janitor must be stopped by calling Close, once the store is no longer
+// used.
+type TTLMessage struct {
+	ttl     time.Duration
+	maxMsgs int
+
+	channels map[discord.ChannelID][]ttlMessage
+	mutex    sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type ttlMessage struct {
+	message discord.Message
+	expires time.Time
+}
+
+var _ store.MessageStore = (*TTLMessage)(nil)
+
+// NewTTLMessage creates a new TTLMessage store, that keeps messages for the
+// passed ttl, and at most maxMsgs messages per channel.
+//
+// The janitor sweeps all channels every ttl/2.
+func NewTTLMessage(ttl time.Duration, maxMsgs int) *TTLMessage {
+	s := &TTLMessage{
+		ttl:      ttl,
+		maxMsgs:  maxMsgs,
+		channels: make(map[discord.ChannelID][]ttlMessage),
+		stop:     make(chan struct{}),
+	}
+
+	go s.janitor(ttl / 2)
+
+	return s
+}
+
+// Close stops the janitor of the store.
+func (s *TTLMessage) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+func (s *TTLMessage) janitor(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			s.Sweep()
+		}
+	}
+}
+
+// Sweep removes all expired messages from the store.
+func (s *TTLMessage) Sweep() {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id := range s.channels {
+		s.expire(id, now)
+	}
+}
+
+// expire removes the expired messages of the channel with the passed id.
+// s.mutex must be held.
+func (s *TTLMessage) expire(channelID discord.ChannelID, now time.Time) []ttlMessage {
+	msgs := s.channels[channelID]
+
+	// messages are prepended when they are inserted, so expired messages are
+	// always at the end
+	end := len(msgs)
+	for end > 0 && now.After(msgs[end-1].expires) {
+		end--
+	}
+
+	if end == 0 {
+		delete(s.channels, channelID)
+		return nil
+	}
+
+	if end < len(msgs) {
+		// clear the removed elements, so their contents can be collected
+		for i := end; i < len(msgs); i++ {
+			msgs[i] = ttlMessage{}
+		}
+
+		msgs = msgs[:end]
+		s.channels[channelID] = msgs
+	}
+
+	return msgs
+}
+
+func (s *TTLMessage) Reset() error {
+	s.mutex.Lock()
+	s.channels = make(map[discord.ChannelID][]ttlMessage)
+	s.mutex.Unlock()
+
+	return nil
+}
+
+func (s *TTLMessage) MaxMessages() int {
+	return s.maxMsgs
+}
+
+func (s *TTLMessage) Message(channelID discord.ChannelID, messageID discord.MessageID) (*discord.Message, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, m := range s.expire(channelID, time.Now()) {
+		if m.message.ID == messageID {
+			cp := m.message
+			return &cp, nil
+		}
+	}
+
+	return nil, store.ErrNotFound
+}
+
+func (s *TTLMessage) Messages(channelID discord.ChannelID) ([]discord.Message, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	msgs := s.expire(channelID, time.Now())
+	if msgs == nil {
+		return nil, store.ErrNotFound
+	}
+
+	cp := make([]discord.Message, len(msgs))
+	for i, m := range msgs {
+		cp[i] = m.message
+	}
+
+	return cp, nil
+}
+
+func (s *TTLMessage) MessageSet(message discord.Message) error {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	msgs := s.expire(message.ChannelID, now)
+
+	for i, m := range msgs {
+		if m.message.ID == message.ID {
+			defaultstore.DiffMessage(message, &msgs[i].message)
+			return nil
+		}
+	}
+
+	if s.maxMsgs <= 0 {
+		return nil
+	}
+
+	if len(msgs) >= s.maxMsgs {
+		msgs = msgs[:s.maxMsgs-1]
+	}
+
+	// prepend, so that the latest message is in front
+	msgs = append(msgs, ttlMessage{})
+	copy(msgs[1:], msgs)
+	msgs[0] = ttlMessage{message: message, expires: now.Add(s.ttl)}
+
+	s.channels[message.ChannelID] = msgs
+
+	return nil
+}
+
+func (s *TTLMessage) MessageRemove(channelID discord.ChannelID, messageID discord.MessageID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	msgs := s.expire(channelID, time.Now())
+
+	for i, m := range msgs {
+		if m.message.ID == messageID {
+			copy(msgs[i:], msgs[i+1:])
+			msgs[len(msgs)-1] = ttlMessage{}
+			msgs = msgs[:len(msgs)-1]
+
+			if len(msgs) == 0 {
+				delete(s.channels, channelID)
+			} else {
+				s.channels[channelID] = msgs
+			}
+
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package cabinet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLMessage(t *testing.T) {
+	t.Run("expiry", func(t *testing.T) {
+		s := NewTTLMessage(50*time.Millisecond, 10)
+		defer s.Close()
+
+		require.NoError(t, s.MessageSet(discord.Message{ID: 1, ChannelID: 1}))
+
+		_, err := s.Message(1, 1)
+		require.NoError(t, err)
+
+		time.Sleep(60 * time.Millisecond)
+
+		_, err = s.Message(1, 1)
+		assert.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("janitor", func(t *testing.T) {
+		s := NewTTLMessage(20*time.Millisecond, 10)
+		defer s.Close()
+
+		require.NoError(t, s.MessageSet(discord.Message{ID: 1, ChannelID: 1}))
+
+		assert.Eventually(t, func() bool {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			return len(s.channels) == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("max messages", func(t *testing.T) {
+		s := NewTTLMessage(time.Hour, 2)
+		defer s.Close()
+
+		for id := discord.MessageID(1); id <= 3; id++ {
+			require.NoError(t, s.MessageSet(discord.Message{ID: id, ChannelID: 1}))
+		}
+
+		msgs, err := s.Messages(1)
+		require.NoError(t, err)
+
+		require.Len(t, msgs, 2)
+		assert.Equal(t, discord.MessageID(3), msgs[0].ID)
+		assert.Equal(t, discord.MessageID(2), msgs[1].ID)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		s := NewTTLMessage(time.Hour, 2)
+		defer s.Close()
+
+		require.NoError(t, s.MessageSet(discord.Message{ID: 1, ChannelID: 1, Content: "abc"}))
+		require.NoError(t, s.MessageSet(discord.Message{ID: 1, ChannelID: 1, Content: "def"}))
+
+		msgs, err := s.Messages(1)
+		require.NoError(t, err)
+
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "def", msgs[0].Content)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		s := NewTTLMessage(time.Hour, 2)
+		defer s.Close()
+
+		require.NoError(t, s.MessageSet(discord.Message{ID: 1, ChannelID: 1}))
+		require.NoError(t, s.MessageRemove(1, 1))
+
+		_, err := s.Messages(1)
+		assert.Equal(t, store.ErrNotFound, err)
+	})
+}
@@ -0,0 +1,142 @@
+package state
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/utils/httputil/httpdriver"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrAttachmentTooLarge gets returned by AttachmentDownloader.Download, if
+	// the attachment exceeds the downloader's MaxSize.
+	ErrAttachmentTooLarge = errors.New("state: the attachment exceeds the maximum size")
+	// ErrContentTypeNotAllowed gets returned by
+	// AttachmentDownloader.Download, if the content type of the attachment is
+	// not allowed.
+	ErrContentTypeNotAllowed = errors.New("state: the content type of the attachment is not allowed")
+)
+
+// AttachmentDownloader downloads message attachments.
+// It uses its own HTTP client, so that downloads from the CDN neither use the
+// rate limiter, nor the RetryPolicy or hooks of the State's API client.
+// It is safe for concurrent use.
+type AttachmentDownloader struct {
+	// MaxSize is the maximum size of an attachment in bytes.
+	// If MaxSize is 0, there is no limit.
+	MaxSize int64
+	// ContentTypes is the list of allowed media types, as sent in the
+	// Content-Type header of the response.
+	// An entry ending with a '/', such as "image/", allows all subtypes of
+	// that type.
+	//
+	// If ContentTypes is empty, all content types are allowed.
+	ContentTypes []string
+
+	client httpdriver.Client
+	// sem limits the number of concurrent downloads.
+	// If it is nil, downloads are not limited.
+	sem chan struct{}
+}
+
+// NewAttachmentDownloader creates a new AttachmentDownloader that performs at
+// most maxConcurrent downloads at a time.
+// If maxConcurrent is 0 or less, the number of concurrent downloads is not
+// limited.
+func (s *State) NewAttachmentDownloader(maxConcurrent int) *AttachmentDownloader {
+	d := &AttachmentDownloader{client: httpdriver.NewClient()}
+
+	if maxConcurrent > 0 {
+		d.sem = make(chan struct{}, maxConcurrent)
+	}
+
+	return d
+}
+
+// Download downloads the passed attachment.
+//
+// If the attachment is larger than MaxSize, ErrAttachmentTooLarge will be
+// returned, and if its content type is not allowed,
+// ErrContentTypeNotAllowed.
+func (d *AttachmentDownloader) Download(ctx context.Context, a discord.Attachment) ([]byte, error) {
+	if d.MaxSize > 0 && a.Size > uint64(d.MaxSize) {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	if d.sem != nil {
+		select {
+		case d.sem <- struct{}{}:
+			defer func() { <-d.sem }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	req, err := d.client.NewRequest(ctx, http.MethodGet, a.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body := resp.GetBody()
+	defer body.Close()
+
+	if status := resp.GetStatus(); status < 200 || status > 299 {
+		return nil, errors.Errorf("state: unexpected status code %d when downloading attachment", status)
+	}
+
+	if !d.contentTypeAllowed(resp.GetHeader().Get("Content-Type")) {
+		return nil, ErrContentTypeNotAllowed
+	}
+
+	var r io.Reader = body
+	if d.MaxSize > 0 {
+		r = io.LimitReader(body, d.MaxSize+1)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.MaxSize > 0 && int64(len(data)) > d.MaxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	return data, nil
+}
+
+// contentTypeAllowed checks if the passed value of a Content-Type header is
+// allowed.
+func (d *AttachmentDownloader) contentTypeAllowed(contentType string) bool {
+	if len(d.ContentTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range d.ContentTypes {
+		if strings.HasSuffix(allowed, "/") {
+			if strings.HasPrefix(mediaType, allowed) {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+
+	return false
+}
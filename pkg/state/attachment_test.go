@@ -0,0 +1,87 @@
+package state
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentDownloader_Download(t *testing.T) {
+	newServer := func(t *testing.T, status int, contentType, body string) (*httptest.Server, *int32) {
+		t.Helper()
+
+		var requests int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv, &requests
+	}
+
+	t.Run("success", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		srv, _ := newServer(t, http.StatusOK, "image/png", "abc")
+
+		d := s.NewAttachmentDownloader(1)
+		d.ContentTypes = []string{"image/"}
+
+		data, err := d.Download(context.Background(), discord.Attachment{URL: srv.URL})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("abc"), data)
+	})
+
+	t.Run("content type not allowed", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		srv, _ := newServer(t, http.StatusOK, "text/plain; charset=utf-8", "abc")
+
+		d := s.NewAttachmentDownloader(0)
+		d.ContentTypes = []string{"image/", "video/mp4"}
+
+		_, err := d.Download(context.Background(), discord.Attachment{URL: srv.URL})
+		assert.Equal(t, ErrContentTypeNotAllowed, err)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		srv, _ := newServer(t, http.StatusOK, "image/png", "abcdef")
+
+		d := s.NewAttachmentDownloader(0)
+		d.MaxSize = 3
+
+		// the reported size is checked before downloading
+		_, err := d.Download(context.Background(), discord.Attachment{URL: srv.URL, Size: 6})
+		assert.Equal(t, ErrAttachmentTooLarge, err)
+
+		// the actual size is checked while downloading
+		_, err = d.Download(context.Background(), discord.Attachment{URL: srv.URL, Size: 2})
+		assert.Equal(t, ErrAttachmentTooLarge, err)
+	})
+
+	t.Run("api retry policy not used", func(t *testing.T) {
+		_, s := NewMocker(t)
+		s.SetRetryPolicy(RetryPolicy{MaxRetries: 3})
+
+		srv, requests := newServer(t, http.StatusServiceUnavailable, "text/plain", "")
+
+		d := s.NewAttachmentDownloader(0)
+
+		_, err := d.Download(context.Background(), discord.Attachment{URL: srv.URL})
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+}
@@ -0,0 +1,172 @@
+package state
+
+import (
+	"context"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/state/store"
+)
+
+// IsBanned checks if the user with the passed id is banned from the guild with
+// the passed id, using the ban cache.
+//
+// If the bans of the guild aren't cached, i.e. WarmBans wasn't called for the
+// guild, store.ErrNotFound will be returned.
+//
+// The cached bans are kept up to date using GuildBanAddEvents and
+// GuildBanRemoveEvents.
+// Since the gateway doesn't send the reason of a ban, the reason of bans
+// added through GuildBanAddEvents is fetched from the API in the background.
+// Until then, or if fetching fails, e.g. because the bot lacks the ban
+// members permission, the Reason of the ban is empty.
+//
+// The bans of a guild stay cached across reconnects, as long as the guild
+// is still part of the ReadyEvent.
+// Bans changed while the bot was disconnected are not reflected, and
+// WarmBans must be called again to account for them.
+func (s *State) IsBanned(guildID discord.GuildID, userID discord.UserID) (bool, error) {
+	_, ok, guildOK := s.bans.ban(guildID, userID)
+	if !guildOK {
+		return false, store.ErrNotFound
+	}
+
+	return ok, nil
+}
+
+// updateBans updates the ban cache using the passed gateway event.
+func (s *State) updateBans(e interface{}) {
+	s.bans.onEvent(e)
+
+	add, ok := e.(*gateway.GuildBanAddEvent)
+	if !ok || !s.bans.cached(add.GuildID) {
+		return
+	}
+
+	s.openMutex.RLock()
+	ctx := s.ctx
+	s.openMutex.RUnlock()
+
+	if ctx == nil { // not opened
+		ctx = context.Background()
+	}
+
+	s.wg.Add(1)
+
+	// Use the context of the EventHandler, so that Close doesn't have to wait
+	// for the request.
+	go func() {
+		defer s.wg.Done()
+
+		b, err := s.WithContext(ctx).GetBan(add.GuildID, add.User.ID)
+		if err != nil {
+			return
+		}
+
+		s.bans.update(add.GuildID, *b)
+	}()
+}
+
+// banStore is a cache for the bans of guilds.
+// Since the Cabinet has no store for bans, they are kept separately.
+type banStore struct {
+	// bans contains the cached bans of each guild.
+	// If a guild is present, its bans were fetched completely.
+	bans  map[discord.GuildID]map[discord.UserID]discord.Ban
+	mutex sync.RWMutex
+}
+
+func newBanStore() *banStore {
+	return &banStore{bans: make(map[discord.GuildID]map[discord.UserID]discord.Ban)}
+}
+
+// set replaces the bans of the guild with the passed id.
+func (s *banStore) set(guildID discord.GuildID, bans []discord.Ban) {
+	m := make(map[discord.UserID]discord.Ban, len(bans))
+
+	for _, b := range bans {
+		m[b.User.ID] = b
+	}
+
+	s.mutex.Lock()
+	s.bans[guildID] = m
+	s.mutex.Unlock()
+}
+
+// update replaces the passed ban, if the user is still banned.
+func (s *banStore) update(guildID discord.GuildID, b discord.Ban) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if bans, ok := s.bans[guildID]; ok {
+		if _, ok := bans[b.User.ID]; ok {
+			bans[b.User.ID] = b
+		}
+	}
+}
+
+// cached checks if the bans of the guild with the passed id are cached.
+func (s *banStore) cached(guildID discord.GuildID) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, ok := s.bans[guildID]
+	return ok
+}
+
+// ban returns the ban of the user with the passed id.
+// If the bans of the guild aren't cached, guildOK will be false.
+// If the bans are cached, but the user is not banned, ok will be false.
+func (s *banStore) ban(guildID discord.GuildID, userID discord.UserID) (b discord.Ban, ok, guildOK bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	bans, guildOK := s.bans[guildID]
+	if !guildOK {
+		return discord.Ban{}, false, false
+	}
+
+	b, ok = bans[userID]
+	return b, ok, true
+}
+
+// onEvent updates the banStore using the passed gateway event.
+func (s *banStore) onEvent(e interface{}) {
+	switch e := e.(type) {
+	case *gateway.ReadyEvent:
+		guilds := make(map[discord.GuildID]struct{}, len(e.Guilds))
+		for _, g := range e.Guilds {
+			guilds[g.ID] = struct{}{}
+		}
+
+		// remove the guilds we left, while we were disconnected
+		s.mutex.Lock()
+		for guildID := range s.bans {
+			if _, ok := guilds[guildID]; !ok {
+				delete(s.bans, guildID)
+			}
+		}
+		s.mutex.Unlock()
+	case *gateway.GuildDeleteEvent:
+		if !e.Unavailable {
+			s.mutex.Lock()
+			delete(s.bans, e.ID)
+			s.mutex.Unlock()
+		}
+	case *gateway.GuildBanAddEvent:
+		s.mutex.Lock()
+		// only add the ban if the guild is cached, otherwise we might
+		// wrongfully report the bans of a guild as cached
+		if bans, ok := s.bans[e.GuildID]; ok {
+			bans[e.User.ID] = discord.Ban{User: e.User}
+		}
+		s.mutex.Unlock()
+	case *gateway.GuildBanRemoveEvent:
+		s.mutex.Lock()
+		if bans, ok := s.bans[e.GuildID]; ok {
+			delete(bans, e.User.ID)
+		}
+		s.mutex.Unlock()
+	}
+}
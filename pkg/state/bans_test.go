@@ -0,0 +1,84 @@
+package state
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_IsBanned(t *testing.T) {
+	t.Run("not cached", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		_, err := s.IsBanned(1, 2)
+		assert.Error(t, err)
+	})
+
+	t.Run("ban add", func(t *testing.T) {
+		m, s := NewMocker(t)
+
+		ban := discord.Ban{User: discord.User{ID: 2}, Reason: "abc"}
+		m.GetBan(1, ban)
+
+		s.bans.set(1, nil)
+
+		events := make(chan interface{}, 1)
+		s.EventHandler.Open(events)
+
+		events <- &gateway.GuildBanAddEvent{GuildID: 1, User: ban.User}
+
+		assert.Eventually(t, func() bool {
+			b, ok, _ := s.bans.ban(1, 2)
+			return ok && b.Reason == ban.Reason
+		}, time.Second, time.Millisecond)
+
+		s.EventHandler.Close()
+
+		banned, err := s.IsBanned(1, 2)
+		assert.NoError(t, err)
+		assert.True(t, banned)
+	})
+
+	t.Run("close cancels fetch", func(t *testing.T) {
+		m, s := NewMocker(t)
+
+		fetching := make(chan struct{})
+
+		m.MockAPI("GetBan", http.MethodGet, "/guilds/1/bans/2",
+			func(w http.ResponseWriter, r *http.Request, t *testing.T) {
+				close(fetching)
+				<-r.Context().Done()
+			})
+
+		s.bans.set(1, nil)
+
+		events := make(chan interface{}, 1)
+		s.EventHandler.Open(events)
+
+		events <- &gateway.GuildBanAddEvent{GuildID: 1, User: discord.User{ID: 2}}
+
+		<-fetching
+
+		closed := make(chan struct{})
+
+		go func() {
+			s.EventHandler.Close()
+			close(closed)
+		}()
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close waited for the ban to be fetched")
+		}
+
+		// the ban is still known, but without reason
+		banned, err := s.IsBanned(1, 2)
+		assert.NoError(t, err)
+		assert.True(t, banned)
+	})
+}
@@ -1,11 +1,26 @@
 package state
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
 // Base is the base of all events.
 type Base struct {
 	vars    map[interface{}]interface{}
 	varsMut sync.RWMutex
+
+	// ctx is the context of the event.
+	// It is nil, if no context was set.
+	ctx context.Context
+
+	// shardID is the id of the shard that received the event.
+	shardID int
+
+	// receivedAt is the time the event was received from the gateway.
+	// It is zero for events that weren't received from the gateway.
+	receivedAt time.Time
 }
 
 // NewBase creates a new Base.
@@ -14,13 +29,59 @@ func NewBase() *Base {
 }
 
 func (b *Base) copy() *Base {
+	b.varsMut.RLock()
+	defer b.varsMut.RUnlock()
+
 	cp := make(map[interface{}]interface{}, len(b.vars))
 
 	for k, v := range b.vars {
 		cp[k] = v
 	}
 
-	return &Base{vars: cp}
+	return &Base{vars: cp, ctx: b.ctx, shardID: b.shardID, receivedAt: b.receivedAt}
+}
+
+// ShardID returns the id of the shard that received the event.
+// It is 0, if the event wasn't received from the gateway, or if no shard was
+// set in the Identifier of the gateway.
+func (b *Base) ShardID() int {
+	return b.shardID
+}
+
+func (b *Base) base() *Base { return b }
+
+// Context returns the context of the event.
+// It is canceled once the EventHandler is closed, or the HandlerTimeout of
+// the EventHandler expires.
+//
+// If the event wasn't dispatched yet, context.Background is returned.
+func (b *Base) Context() context.Context {
+	if ctx, ok := b.lookupContext(); ok {
+		return ctx
+	}
+
+	return context.Background()
+}
+
+// lookupContext returns the context of the Base, and whether one was set.
+func (b *Base) lookupContext() (context.Context, bool) {
+	b.varsMut.RLock()
+	defer b.varsMut.RUnlock()
+
+	return b.ctx, b.ctx != nil
+}
+
+// SetContext replaces the context of the event, e.g. to attach values, such
+// as trace ids, to it.
+// The context should be derived from the one returned by Context, so that
+// the cancellation of the event is preserved.
+//
+// When called in a global middleware, all handlers will receive the context.
+// When called in a middleware of a handler, only that handler will.
+func (b *Base) SetContext(ctx context.Context) {
+	b.varsMut.Lock()
+	b.ctx = ctx
+	b.varsMut.Unlock()
 }
 
 // Set stores the passed element under the given key.
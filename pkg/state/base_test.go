@@ -0,0 +1,62 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type baseTestKey struct{}
+
+func TestBase_copy(t *testing.T) {
+	t.Run("independent", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), baseTestKey{}, "abc")
+
+		b := NewBase()
+		b.Set("a", 1)
+		b.SetContext(ctx)
+
+		cp := b.copy()
+		cp.Set("a", 2)
+		cp.Set("b", 3)
+
+		assert.Equal(t, 1, b.Get("a"))
+		assert.Nil(t, b.Get("b"))
+
+		assert.Equal(t, 2, cp.Get("a"))
+		assert.Equal(t, ctx, cp.Context())
+	})
+
+	t.Run("concurrent set", func(t *testing.T) {
+		b := NewBase()
+		b.Set(-1, -1)
+
+		start := make(chan struct{})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			<-start
+
+			for i := 0; i < 1000; i++ {
+				b.Set(i, i)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			<-start
+
+			for i := 0; i < 1000; i++ {
+				b.copy()
+			}
+		}()
+
+		close(start)
+		wg.Wait()
+	})
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/pkg/errors"
+)
+
+// BatchChannelStore is an optional interface that a store.ChannelStore may
+// implement to store multiple channels at once.
+type BatchChannelStore interface {
+	// ChannelsSet stores all passed channels.
+	ChannelsSet(channels []discord.Channel) error
+}
+
+// BatchMemberStore is an optional interface that a store.MemberStore may
+// implement to store multiple members of a guild at once.
+type BatchMemberStore interface {
+	// MembersSet stores all passed members of the guild with the passed id.
+	MembersSet(guildID discord.GuildID, members []discord.Member) error
+}
+
+// BatchPresenceStore is an optional interface that a store.PresenceStore may
+// implement to store multiple presences of a guild at once.
+type BatchPresenceStore interface {
+	// PresencesSet stores all passed presences of the guild with the passed
+	// id.
+	PresencesSet(guildID discord.GuildID, presences []gateway.Presence) error
+}
+
+// BatchVoiceStateStore is an optional interface that a
+// store.VoiceStateStore may implement to store multiple voice states of a
+// guild at once.
+type BatchVoiceStateStore interface {
+	// VoiceStatesSet stores all passed voice states of the guild with the
+	// passed id.
+	VoiceStatesSet(guildID discord.GuildID, voiceStates []discord.VoiceState) error
+}
+
+// storeBatched checks if the passed event is a GuildCreateEvent, and if so,
+// stores all parts of the guild whose store supports batch writes.
+// It returns a copy of the event without the stored parts, that should be
+// used to update the state.
+// Otherwise, it returns the event unchanged.
+func (s *State) storeBatched(e interface{}) interface{} {
+	gc, ok := e.(*gateway.GuildCreateEvent)
+	if !ok || gc.Unavailable {
+		return e
+	}
+
+	cp := *gc
+
+	if len(cp.Channels) > 0 {
+		if cs, ok := s.Cabinet.ChannelStore.(BatchChannelStore); ok {
+			channels := make([]discord.Channel, len(cp.Channels))
+			for i, c := range cp.Channels {
+				c.GuildID = cp.ID // guild channels are sent without a guild id
+				channels[i] = c
+			}
+
+			if err := cs.ChannelsSet(channels); err != nil {
+				s.stateLog(errors.Wrap(err, "failed to batch set guild channels"))
+			}
+
+			cp.Channels = nil
+		}
+	}
+
+	if len(cp.Members) > 0 {
+		if ms, ok := s.Cabinet.MemberStore.(BatchMemberStore); ok {
+			if err := ms.MembersSet(cp.ID, cp.Members); err != nil {
+				s.stateLog(errors.Wrap(err, "failed to batch set guild members"))
+			}
+
+			cp.Members = nil
+		}
+	}
+
+	if len(cp.Presences) > 0 {
+		if ps, ok := s.Cabinet.PresenceStore.(BatchPresenceStore); ok {
+			if err := ps.PresencesSet(cp.ID, cp.Presences); err != nil {
+				s.stateLog(errors.Wrap(err, "failed to batch set guild presences"))
+			}
+
+			cp.Presences = nil
+		}
+	}
+
+	if len(cp.VoiceStates) > 0 {
+		if vs, ok := s.Cabinet.VoiceStateStore.(BatchVoiceStateStore); ok {
+			if err := vs.VoiceStatesSet(cp.ID, cp.VoiceStates); err != nil {
+				s.stateLog(errors.Wrap(err, "failed to batch set guild voice states"))
+			}
+
+			cp.VoiceStates = nil
+		}
+	}
+
+	return &cp
+}
@@ -0,0 +1,57 @@
+package state
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// eventBudget tracks the time passed since the dispatch of a single event
+// started.
+type eventBudget struct {
+	budget time.Duration
+	start  time.Time
+
+	// exceeded is 1, if the budget was found to be exceeded.
+	exceeded int32
+}
+
+// newEventBudget creates a new eventBudget for an event, whose dispatch
+// started just now.
+// If EventBudget is not set, newEventBudget returns nil.
+func (h *EventHandler) newEventBudget() *eventBudget {
+	if h.EventBudget <= 0 {
+		return nil
+	}
+
+	return &eventBudget{budget: h.EventBudget, start: time.Now()}
+}
+
+// check returns the time spent since the start of the dispatch, and whether
+// the budget is exceeded.
+// first will only be true for the first call that found the budget to be
+// exceeded.
+func (b *eventBudget) check() (spent time.Duration, exceeded, first bool) {
+	if b == nil {
+		return 0, false, false
+	}
+
+	spent = time.Since(b.start)
+	if spent <= b.budget {
+		return spent, false, false
+	}
+
+	return spent, true, atomic.CompareAndSwapInt32(&b.exceeded, 0, 1)
+}
+
+// checkBudget checks if the passed eventBudget is exceeded, and calls the
+// BudgetHandler, if it is the first to notice.
+// e is the event, and handlerName the name of the handler that is about to
+// be started or just finished.
+func (h *EventHandler) checkBudget(b *eventBudget, e interface{}, handlerName string) bool {
+	spent, exceeded, first := b.check()
+	if first && h.BudgetHandler != nil {
+		h.BudgetHandler(e, handlerName, spent)
+	}
+
+	return exceeded
+}
@@ -0,0 +1,54 @@
+package state
+
+import "github.com/diamondburned/arikawa/v2/state/store"
+
+// DisableCaching stops the State from storing the passed resources, by
+// replacing their stores in the Cabinet with no-op implementations.
+// Lookups of disabled resources will always miss the cache, and therefore
+// fall through to the API, unless disabled using SetReadThrough.
+// Old fields of events that rely on disabled resources will always be nil.
+//
+// Disabling ResourcePresences also skips the lookup of the Old presence of
+// PresenceUpdateEvents.
+// This is independent of the presence intent, so PresenceUpdateEvents are
+// still received.
+//
+// Disabling ResourceGuilds or ResourceChannels is possible, but will
+// cause most lookups to require an API request.
+//
+// DisableCaching must be called before the State is opened.
+func (s *State) DisableCaching(r Resource) {
+	s.noCaching |= r
+
+	if r.Has(ResourceChannels) {
+		s.Cabinet.ChannelStore = store.Noop
+	}
+
+	if r.Has(ResourceEmojis) {
+		s.Cabinet.EmojiStore = store.Noop
+	}
+
+	if r.Has(ResourceGuilds) {
+		s.Cabinet.GuildStore = store.Noop
+	}
+
+	if r.Has(ResourceMembers) {
+		s.Cabinet.MemberStore = store.Noop
+	}
+
+	if r.Has(ResourceMessages) {
+		s.Cabinet.MessageStore = store.Noop
+	}
+
+	if r.Has(ResourcePresences) {
+		s.Cabinet.PresenceStore = store.Noop
+	}
+
+	if r.Has(ResourceRoles) {
+		s.Cabinet.RoleStore = store.Noop
+	}
+
+	if r.Has(ResourceVoiceStates) {
+		s.Cabinet.VoiceStateStore = store.Noop
+	}
+}
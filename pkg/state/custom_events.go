@@ -1,6 +1,73 @@
 package state
 
+import (
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
 // CloseEvent gets dispatched when the gateway closes.
 type CloseEvent struct {
 	*Base
 }
+
+// UserTypingEvent is a custom event that gets dispatched, when a user starts
+// typing in a channel.
+// In contrast to TypingStartEvents, which are sent repeatedly while a user is
+// typing, it gets dispatched only once per typing session.
+//
+// It is only dispatched, if typing aggregation was enabled using
+// State.EnableTypingAggregation.
+type UserTypingEvent struct {
+	*gateway.TypingStartEvent
+	*Base
+}
+
+// UserStoppedTypingEvent is a custom event that gets dispatched, when a user
+// that started typing, as indicated by a UserTypingEvent, stops typing.
+//
+// Since Discord doesn't send an event when a user stops typing, this is
+// inferred either by the user sending a message, or by the user not sending a
+// TypingStartEvent for the timeout passed to State.EnableTypingAggregation.
+//
+// It is only dispatched, if typing aggregation was enabled using
+// State.EnableTypingAggregation.
+type UserStoppedTypingEvent struct {
+	*Base
+
+	ChannelID discord.ChannelID
+	GuildID   discord.GuildID
+	UserID    discord.UserID
+
+	// Message is the message the user sent, causing them to stop typing.
+	// If the user timed out, Message will be nil.
+	Message *discord.Message
+}
+
+// GuildFullyCachedEvent is a custom event that gets dispatched, when the
+// members and presences of a large guild, whose caching was deferred, were
+// stored in the Cabinet.
+//
+// It is only dispatched, if deferred caching was enabled using
+// State.DeferLargeGuildCaching.
+type GuildFullyCachedEvent struct {
+	*Base
+
+	GuildID discord.GuildID
+}
+
+// ShardRecoveredEvent is a custom event that gets dispatched, when a shard
+// whose heartbeats stalled was restarted by the shard monitor.
+//
+// It is only dispatched, if the shard monitor was started using
+// State.MonitorShards.
+type ShardRecoveredEvent struct {
+	*Base
+
+	// ShardID is the id of the recovered shard.
+	ShardID int
+	// LastHeartbeatAck is the time the last heartbeat was acknowledged
+	// before the shard was restarted.
+	LastHeartbeatAck time.Time
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"reflect"
+)
+
+// Diff returns the names of the fields of the channel that changed.
+// If Old is nil, Diff returns nil.
+func (e *ChannelUpdateEvent) Diff() []string {
+	if e.Old == nil {
+		return nil
+	}
+
+	return diffFields(e.Old, &e.Channel, false)
+}
+
+// Diff returns the names of the fields of the guild that changed.
+// If Old is nil, Diff returns nil.
+func (e *GuildUpdateEvent) Diff() []string {
+	if e.Old == nil {
+		return nil
+	}
+
+	return diffFields(e.Old, &e.Guild, false)
+}
+
+// Diff returns the names of the fields of the member that changed.
+// If Old is nil, Diff returns nil.
+func (e *GuildMemberUpdateEvent) Diff() []string {
+	if e.Old == nil {
+		return nil
+	}
+
+	updated := *e.Old
+	e.Update(&updated)
+
+	return diffFields(e.Old, &updated, false)
+}
+
+// Diff returns the names of the fields of the role that changed.
+// If Old is nil, Diff returns nil.
+func (e *GuildRoleUpdateEvent) Diff() []string {
+	if e.Old == nil {
+		return nil
+	}
+
+	return diffFields(e.Old, &e.Role, false)
+}
+
+// Diff returns the names of the fields of the message that changed.
+// If Old is nil, Diff returns nil.
+//
+// Since Discord may send partial message updates, fields that are zero in the
+// update are not considered changed.
+func (e *MessageUpdateEvent) Diff() []string {
+	if e.Old == nil {
+		return nil
+	}
+
+	return diffFields(e.Old, &e.Message, true)
+}
+
+// Diff returns the names of the fields of the presence that changed.
+// If Old is nil, Diff returns nil.
+//
+// The User field is never considered changed, since Discord only guarantees
+// its ID to be set.
+func (e *PresenceUpdateEvent) Diff() []string {
+	if e.Old == nil {
+		return nil
+	}
+
+	return diffFields(e.Old, &e.Presence, false, "User")
+}
+
+// Diff returns the names of the fields of the voice state that changed.
+// If Old is nil, Diff returns nil.
+func (e *VoiceStateUpdateEvent) Diff() []string {
+	if e.Old == nil {
+		return nil
+	}
+
+	return diffFields(e.Old, &e.VoiceState, false)
+}
+
+// diffFields returns the names of the fields that differ between the structs
+// pointed to by old and new, which must be of the same type.
+// If partial is true, fields that are zero in new are skipped.
+// Fields named in ignore are always skipped.
+func diffFields(old, new interface{}, partial bool, ignore ...string) []string {
+	oldVal := reflect.ValueOf(old).Elem()
+	newVal := reflect.ValueOf(new).Elem()
+
+	var changed []string
+
+Fields:
+	for i := 0; i < oldVal.NumField(); i++ {
+		f := oldVal.Type().Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+
+		for _, name := range ignore {
+			if f.Name == name {
+				continue Fields
+			}
+		}
+
+		newField := newVal.Field(i)
+		if partial && newField.IsZero() {
+			continue
+		}
+
+		if !reflect.DeepEqual(oldVal.Field(i).Interface(), newField.Interface()) {
+			changed = append(changed, f.Name)
+		}
+	}
+
+	return changed
+}
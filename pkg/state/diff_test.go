@@ -0,0 +1,53 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelUpdateEvent_Diff(t *testing.T) {
+	t.Run("no old", func(t *testing.T) {
+		e := &ChannelUpdateEvent{ChannelUpdateEvent: &gateway.ChannelUpdateEvent{}}
+		assert.Nil(t, e.Diff())
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		e := &ChannelUpdateEvent{
+			ChannelUpdateEvent: &gateway.ChannelUpdateEvent{
+				Channel: discord.Channel{ID: 1, Name: "def", Topic: ""},
+			},
+			Old: &discord.Channel{ID: 1, Name: "abc", Topic: "ghi"},
+		}
+
+		assert.Equal(t, []string{"Name", "Topic"}, e.Diff())
+	})
+}
+
+func TestMessageUpdateEvent_Diff(t *testing.T) {
+	// message updates may be partial, so the cleared content isn't reported
+	e := &MessageUpdateEvent{
+		MessageUpdateEvent: &gateway.MessageUpdateEvent{
+			Message: discord.Message{ID: 1, Pinned: true},
+		},
+		Old: &discord.Message{ID: 1, Content: "abc"},
+	}
+
+	assert.Equal(t, []string{"Pinned"}, e.Diff())
+}
+
+func TestPresenceUpdateEvent_Diff(t *testing.T) {
+	e := &PresenceUpdateEvent{
+		PresenceUpdateEvent: &gateway.PresenceUpdateEvent{
+			Presence: gateway.Presence{User: discord.User{ID: 1}, Status: gateway.IdleStatus},
+		},
+		Old: &gateway.Presence{
+			User:   discord.User{ID: 1, Username: "abc"},
+			Status: gateway.OnlineStatus,
+		},
+	}
+
+	assert.Equal(t, []string{"Status"}, e.Diff())
+}
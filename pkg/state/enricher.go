@@ -0,0 +1,57 @@
+package state
+
+import "reflect"
+
+// Enricher wraps a per-handler middleware that enriches the event before it
+// is passed to the handler, e.g. by attaching parsed command arguments.
+//
+// By default, handlers share the gateway event embedded in the events they
+// receive, and mutating it is therefore not safe.
+// If at least one of the middlewares of a handler is an Enricher, the handler
+// receives a copy of the embedded gateway event, that all of its middlewares
+// may freely mutate.
+// Mutations are visible to the handler, but not to other handlers or global
+// middlewares.
+//
+// Note that the copy is shallow: slices, maps and pointers inside the gateway
+// event, such as a message's embeds, are still shared, and must be replaced
+// rather than modified.
+//
+// Enrichers can be passed to AddHandler and its variants like any other
+// middleware.
+type Enricher struct {
+	// Middleware is the wrapped middleware.
+	Middleware interface{}
+}
+
+// Enrich wraps the passed middleware in an Enricher.
+func Enrich(middleware interface{}) Enricher {
+	return Enricher{Middleware: middleware}
+}
+
+// isolateEvent replaces the gateway events embedded in the passed event with
+// shallow copies.
+// ev must be a pointer to an event created by copyEvent.
+func isolateEvent(ev reflect.Value) {
+	e := ev.Elem()
+	t := e.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.Anonymous || f.Type == baseType || f.Type.Kind() != reflect.Ptr ||
+			f.Type.Elem().Kind() != reflect.Struct || e.Field(i).IsNil() {
+			continue
+		}
+
+		// situation-specific events embed the event they are derived from,
+		// which was already copied by copyEvent
+		if f.Type.Elem().PkgPath() == t.PkgPath() {
+			isolateEvent(e.Field(i))
+			continue
+		}
+
+		cp := reflect.New(f.Type.Elem())
+		cp.Elem().Set(e.Field(i).Elem())
+		e.Field(i).Set(cp)
+	}
+}
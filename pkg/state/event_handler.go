@@ -1,11 +1,19 @@
 package state
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/state/store"
 )
 
 var (
@@ -22,12 +30,44 @@ var (
 	// MustAddMiddleware if the middleware func is invalid.
 	ErrInvalidMiddleware = errors.New("state: the passed middleware does not match the type of the handler")
 
+	// ErrInvalidAutoAdd gets returned by TryAutoAddHandlers, if the passed
+	// value is not a pointer.
+	ErrInvalidAutoAdd = errors.New("state: the value to scan for handlers must be a pointer")
+
 	// Filtered should be returned if a filter blocks an event.
+	// To state the reason the event was filtered, a *FilterError can be
+	// returned instead.
 	Filtered = errors.New("filtered") //nolint:golint,stylecheck
 )
 
 type (
+	// AutoAddError is the error returned by TryAutoAddHandlers, if methods
+	// were skipped.
+	AutoAddError struct {
+		// Skipped are the methods that were skipped.
+		Skipped []SkippedHandler
+	}
+
+	// SkippedHandler is a method that was skipped by TryAutoAddHandlers.
+	SkippedHandler struct {
+		// Name is the name of the method.
+		Name string
+		// Err is the reason the method was skipped, either
+		// ErrInvalidHandler or ErrInvalidMiddleware.
+		Err error
+	}
+
 	EventHandler struct {
+		// dropped is the number of events dropped from the event queue.
+		// It is the first field, to guarantee 64-bit alignment for atomic
+		// access.
+		dropped uint64
+		// ready is 1, if a Ready event was received since the EventHandler
+		// was opened.
+		ready uint32
+		// open is 1, if the EventHandler is open.
+		open uint32
+
 		s  *State
 		sv reflect.Value
 
@@ -37,16 +77,207 @@ type (
 		globalMiddlewares      map[reflect.Type][]globalMiddleware
 		globalMiddlewaresMutex sync.RWMutex
 
+		afterMiddlewares      []AfterMiddleware
+		afterMiddlewaresMutex sync.RWMutex
+
+		// internalHandlers are the handlers added through
+		// addInternalHandler, by their serial.
+		internalHandlers       map[uint64]func(gatewayEvent interface{})
+		internalHandlersSerial uint64
+		internalHandlersMutex  sync.RWMutex
+
 		wg sync.WaitGroup
 
 		ErrorHandler func(err error)
 		PanicHandler func(err interface{})
 
+		// ErrorHandlerFull, if not nil, is called instead of ErrorHandler.
+		// In addition to the error, it receives the event that was being
+		// handled, and the name of the handler or middleware func that
+		// returned the error, as reported by runtime.FuncForPC.
+		//
+		// Errors that don't originate from a handler, such as those of a
+		// SendQueue, are reported with the event they relate to, or nil, and
+		// the name of the type that produced them as handlerName.
+		ErrorHandlerFull func(err error, e interface{}, handlerName string)
+		// PanicHandlerFull, if not nil, is called instead of PanicHandler.
+		// In addition to the recovered value, it receives the stack trace of
+		// the panicking goroutine, and the event that was being handled.
+		PanicHandlerFull func(rec interface{}, stack []byte, e interface{})
+
+		// StoreErrorHandler, if not nil, is called every time a Cabinet
+		// lookup made to fill the Old field of an event fails with an error
+		// other than store.ErrNotFound.
+		// e is the gateway event, whose Old field couldn't be filled, and
+		// which will be dispatched with a nil Old field.
+		StoreErrorHandler func(err error, e interface{})
+		// StateErrorHandler, if not nil, is called every time updating the
+		// Cabinet with a gateway event fails.
+		// e is the gateway event that caused the update.
+		//
+		// StateErrorHandler replaces the StateLog of the arikawa State,
+		// which doesn't provide the event.
+		StateErrorHandler func(err error, e interface{})
+		// stateEvent is the gateway event that is currently used to update
+		// the state.
+		// It is only accessed by the event loop.
+		stateEvent interface{}
+
+		// OnFiltered, if not nil, is called every time a middleware or
+		// handler filters an event by returning Filtered or a *FilterError.
+		// filterName is the name of the func that filtered the event, and
+		// reason the Reason of the *FilterError, or empty, if Filtered was
+		// returned.
+		OnFiltered func(e interface{}, filterName, reason string)
+
+		// LagHandler, if not nil, is called once for every event that carries
+		// a timestamp set by Discord, after the global middlewares were
+		// called, and before the handlers of the event are started.
+		// Currently, these are MessageCreateEvents, MessageUpdateEvents
+		// with an EditedTimestamp, and TypingStartEvents, whose timestamp
+		// only has second precision.
+		//
+		// gatewayLag is the time between Discord creating the event and the
+		// event being received from the gateway.
+		// dispatchLag is the time between the event being received and the
+		// global middlewares having finished.
+		//
+		// Events dispatched manually using Call are not reported.
+		LagHandler func(e interface{}, gatewayLag, dispatchLag time.Duration)
+
+		// EventBudget is the maximum wall-clock time the dispatch of a single
+		// event may take, measured from the start of the dispatch, i.e.
+		// before the global middlewares are called.
+		//
+		// Once the budget of an event is exceeded, BudgetHandler is called,
+		// and handlers of the event that haven't been started yet, will be
+		// skipped.
+		// Handlers that are already running are not interrupted.
+		//
+		// EventBudget is measured from the same point in time as the
+		// HandlerTimeout, but the two are independent: HandlerTimeout only
+		// cancels the context of the event, while EventBudget only skips
+		// handlers.
+		// To skip the remaining handlers once the context was canceled,
+		// EventBudget can be set to the HandlerTimeout.
+		//
+		// If EventBudget is 0, there is no limit.
+		EventBudget time.Duration
+		// BudgetHandler, if not nil, is called once per event, if the
+		// handlers of the event exceed the EventBudget.
+		//
+		// handlerName is the name of the handler that just finished or was
+		// about to be started when the budget was found to be exceeded, and
+		// spent is the time passed since the start of the dispatch.
+		BudgetHandler func(e interface{}, handlerName string, spent time.Duration)
+
+		// SlowHandlerThreshold is the duration after which a handler that is
+		// still executing is considered slow.
+		//
+		// If SlowHandlerThreshold is 0, handlers are never considered slow.
+		SlowHandlerThreshold time.Duration
+		// SlowHandler, if not nil, gets called with the event and the name of
+		// the handler func, once a handler has been executing for longer than
+		// the SlowHandlerThreshold.
+		// It is called while the handler is still executing, so that
+		// handlers that block forever can be detected.
+		SlowHandler func(e interface{}, handlerName string)
+
+		// OnDispatchStart, if not nil, is called with every event, before
+		// the global middlewares are called.
+		OnDispatchStart func(e interface{})
+		// OnDispatchEnd, if not nil, is called with every event, after the
+		// global middlewares and all handlers of the event have finished
+		// executing.
+		// d is the time between the start of the dispatch and the last
+		// handler finishing.
+		OnDispatchEnd func(e interface{}, d time.Duration)
+
+		// FaultInjection, if not nil, configures faults that are injected
+		// into the events received from the gateway.
+		// It must only be used for testing.
+		//
+		// Changes only take effect when calling Open.
+		FaultInjection *FaultInjection
+
+		// Metrics, if not nil, is used to report the number of dispatched
+		// events and the execution durations of handlers.
+		Metrics Metrics
+
+		// HandlerTimeout is the maximum time the handlers of a single event
+		// should take.
+		// Once it expires, the context returned by Base.Context is canceled.
+		// Handlers are expected to respect the cancellation themselves; they
+		// are not interrupted.
+		//
+		// If HandlerTimeout is 0, the context is only canceled when the
+		// EventHandler is closed.
+		HandlerTimeout time.Duration
+
+		// MaxConcurrentHandlers is the maximum number of handlers that are
+		// executed concurrently.
+		//
+		// If MaxConcurrentHandlers is greater than 0, events received from
+		// the gateway are dispatched by the event listener itself, and
+		// starting a handler blocks until fewer than MaxConcurrentHandlers
+		// handlers are executing.
+		// Hence, if all handlers are busy, no further events are read from
+		// the gateway, or the event queue, if there is one.
+		// Therefore, handlers must not wait for handlers of other events,
+		// such as events they dispatched using Call, as this may lead to a
+		// deadlock.
+		//
+		// Changes only take effect when calling Open.
+		MaxConcurrentHandlers int
+
+		// QueueSize is the size of the queue used to buffer the events
+		// received from the gateway, before they are processed.
+		//
+		// If QueueSize is 0, events are read directly from the gateway, and
+		// slow state updates may stall the gateway.
+		//
+		// Changes only take effect when calling Open.
+		QueueSize int
+		// OverflowPolicy is the policy used, if the queue is full.
+		// Note that events dropped by the queue won't update the state.
+		//
+		// Defaults to OverflowBlock.
+		//
+		// Changes only take effect when calling Open.
+		OverflowPolicy OverflowPolicy
+
 		// currentSerial is the next available serial number.
 		// This is used to preserve the order of global middlewares.
 		currentSerial uint64
 
+		// queue is the event queue, if there is one.
+		// It stores a chan interface{}.
+		queue atomic.Value
+
+		// openMutex guards the fields below, which are replaced every time
+		// the EventHandler is opened.
+		openMutex sync.RWMutex
+		// events is the channel passed to Open.
+		events <-chan interface{}
 		closer chan<- struct{}
+		// stopIntake is closed to make the event listener stop reading from
+		// events, and return once all buffered events were dispatched.
+		stopIntake chan<- struct{}
+		// listening is closed, once the event listener returned.
+		listening <-chan struct{}
+		// ctx is the parent context of all events dispatched while the
+		// EventHandler is open.
+		// It is canceled when the EventHandler is closed.
+		ctx    context.Context
+		cancel context.CancelFunc
+		// handlerSlots is the semaphore limiting the number of concurrently
+		// executing handlers.
+		// If it is nil, there is no limit.
+		handlerSlots chan struct{}
+
+		// lifecycleMutex serializes Open, Close and Restart, so that a
+		// Restart can't reopen the EventHandler, after it was closed.
+		lifecycleMutex sync.Mutex
 	}
 
 	globalMiddleware struct {
@@ -54,11 +285,44 @@ type (
 		serial     uint64
 	}
 
+	// AfterMiddleware is a middleware that is called after a handler func
+	// returned.
+	//
+	// e is the event the handler was called with, err is the error returned
+	// by the handler, and d is the time it took the handler to execute.
+	AfterMiddleware func(s *State, e interface{}, err error, d time.Duration)
+
+	// HandlerOptions are the options used to add a handler through
+	// AddHandlerWithOptions.
+	HandlerOptions struct {
+		// Priority is the priority of the handler.
+		//
+		// Handlers with a higher priority are guaranteed to finish executing,
+		// before handlers with a lower priority are started.
+		// Handlers with the same priority are executed concurrently.
+		//
+		// Defaults to 0.
+		Priority int
+		// Predicate, if not nil, is called with the event before the event is
+		// copied for the handler and before the handler's middlewares are
+		// called.
+		// If it returns false, the handler won't be called.
+		//
+		// Since the predicate receives the original event, that is shared
+		// with other handlers, it must not modify the event.
+		Predicate func(e interface{}) bool
+	}
+
 	// genericHandler wraps an event handler alongside it's middlewares.
 	genericHandler struct {
 		handler reflect.Value
 
-		channel bool
+		channel   bool
+		priority  int
+		predicate func(e interface{}) bool
+		// enrich specifies whether the handler has an Enricher, and
+		// therefore needs its own copy of the gateway event.
+		enrich bool
 
 		once *sync.Once
 		rm   func()
@@ -73,60 +337,369 @@ type (
 	}
 )
 
+func (e *AutoAddError) Error() string {
+	names := make([]string, len(e.Skipped))
+
+	for i, s := range e.Skipped {
+		names[i] = s.Name
+	}
+
+	return fmt.Sprintf("state: skipped %d methods that are not valid handlers: %s",
+		len(e.Skipped), strings.Join(names, ", "))
+}
+
 // NewEventHandler creates a new EventHandler.
 func NewEventHandler(s *State) *EventHandler {
 	// make sure state update is blocking
 	s.State.Session.Handler.Synchronous = true
 
-	return &EventHandler{
+	h := &EventHandler{
 		s:                 s,
 		sv:                reflect.ValueOf(s),
 		handlers:          make(map[reflect.Type][]*genericHandler),
 		globalMiddlewares: make(map[reflect.Type][]globalMiddleware),
+		internalHandlers:  make(map[uint64]func(interface{})),
 		ErrorHandler:      func(error) {},
 		PanicHandler:      func(interface{}) {},
 	}
+
+	s.State.StateLog = h.stateLog
+
+	return h
+}
+
+// stateLog is used as the StateLog of the arikawa State.
+// It reports the passed error, along with the event that caused it, to the
+// StateErrorHandler.
+func (h *EventHandler) stateLog(err error) {
+	h.stateError(err, h.stateEvent)
+}
+
+// stateError reports the passed error, that occurred while updating the
+// state with the passed gateway event, to the StateErrorHandler.
+// store.ErrNotFound errors are ignored.
+func (h *EventHandler) stateError(err error, e interface{}) {
+	if h.StateErrorHandler == nil || errors.Is(err, store.ErrNotFound) {
+		return
+	}
+
+	h.StateErrorHandler(err, e)
 }
 
 // Open starts listening for events until the returned closer function is
 // called.
 func (h *EventHandler) Open(events <-chan interface{}) {
-	closer := make(chan struct{})
+	h.lifecycleMutex.Lock()
+	defer h.lifecycleMutex.Unlock()
+
+	h.startListening(events)
+}
+
+// startListening starts listening for events.
+// The lifecycleMutex must be locked.
+func (h *EventHandler) startListening(events <-chan interface{}) {
+	var (
+		closer    = make(chan struct{})
+		stop      = make(chan struct{})
+		listening = make(chan struct{})
+		slots     chan struct{}
+	)
+
+	if h.MaxConcurrentHandlers > 0 {
+		slots = make(chan struct{}, h.MaxConcurrentHandlers)
+	}
+
+	if h.s.typing != nil {
+		h.s.typing.open()
+	}
+
+	h.openMutex.Lock()
+
+	h.events = events
 	h.closer = closer
+	h.stopIntake = stop
+	h.listening = listening
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	h.handlerSlots = slots
+
+	h.openMutex.Unlock()
+
+	atomic.StoreUint32(&h.open, 1)
+
+	// Only the first stage of the pipeline stops reading, once stop is
+	// closed.
+	// All following stages stop, once the stage before them closed its
+	// channel after forwarding all of its buffered events.
+	var stopDirect <-chan struct{} = stop
+
+	if h.FaultInjection != nil {
+		events = h.injectFaults(events, stopDirect, closer)
+		stopDirect = nil
+	}
+
+	if h.QueueSize > 0 {
+		events = h.queueEvents(events, stopDirect, closer)
+		stopDirect = nil
+	}
 
 	go func() {
+		defer close(listening)
+
 		for {
 			select {
 			case <-closer:
 				return
-			case gatewayEvent := <-events:
-				e := h.genEvent(gatewayEvent)
-				if e == nil {
-					break
+			case <-stopDirect: // only set, if we read from the gateway directly
+				return
+			case gatewayEvent, ok := <-events:
+				if !ok { // all buffered events were dispatched
+					return
 				}
 
-				// prevent premature closer between here and when the first handler is called
-				h.wg.Add(1)
-
-				h.s.Session.Call(gatewayEvent) // trigger state update
-
-				go func() {
-					h.Call(e)
-					h.wg.Done()
-				}()
+				h.handleEvent(gatewayEvent, slots)
 			}
 		}
 	}()
 }
 
+// handleEvent updates the state using the passed gateway event, and
+// dispatches the corresponding event.
+// slots are the handler slots of the event listener.
+func (h *EventHandler) handleEvent(gatewayEvent interface{}, slots chan struct{}) {
+	// Events are processed one at a time, and genEvent reads the Old fields
+	// before the state update below is triggered.
+	// Hence, the Old fields always reflect the state right before the event,
+	// regardless of how many events arrive.
+	e := h.genEvent(gatewayEvent)
+	if e == nil {
+		return
+	}
+
+	// prevent premature closer between here and when the first handler is called
+	h.wg.Add(1)
+
+	var (
+		stripped = gatewayEvent
+		job      *guildCacheJob
+	)
+
+	if h.s.guildCache != nil {
+		stripped, job = h.s.guildCache.strip(stripped)
+	}
+
+	h.stateEvent = gatewayEvent
+
+	stripped = h.s.storeBatched(stripped)
+
+	// trigger state update
+	if h.s.guildCache != nil {
+		h.s.guildCache.update(gatewayEvent, stripped)
+	} else {
+		h.s.Session.Call(stripped)
+	}
+
+	h.stateEvent = nil
+
+	if job != nil {
+		h.s.guildCache.enqueue(job)
+	} else {
+		// deferred guilds are tracked once they were processed
+		h.s.trackChunked(gatewayEvent)
+	}
+	h.s.updateBans(gatewayEvent)
+
+	if h.s.typing != nil {
+		h.s.typing.onEvent(gatewayEvent)
+	}
+
+	h.callInternalHandlers(gatewayEvent)
+
+	// if the number of handlers is limited, dispatch directly, so that we
+	// stop reading events, once all handlers are busy
+	if slots != nil {
+		h.Call(e)
+		h.wg.Done()
+	} else {
+		go func() {
+			h.Call(e)
+			h.wg.Done()
+		}()
+	}
+}
+
 // Close stops the event listener and blocks until all handlers have finished
 // executing.
 func (h *EventHandler) Close() {
-	if h.closer != nil {
-		close(h.closer)
-		h.closer = nil
+	h.lifecycleMutex.Lock()
+	defer h.lifecycleMutex.Unlock()
+
+	h.stopListening()
+}
+
+// stopListening stops the event listener and waits for all handlers.
+// The lifecycleMutex must be locked.
+func (h *EventHandler) stopListening() {
+	h.openMutex.Lock()
+
+	if h.closer == nil {
+		h.openMutex.Unlock()
+		return
+	}
 
-		h.wg.Wait()
+	close(h.closer)
+	h.closer = nil
+
+	listening := h.listening
+	cancel := h.cancel
+
+	h.openMutex.Unlock()
+
+	atomic.StoreUint32(&h.ready, 0)
+	atomic.StoreUint32(&h.open, 0)
+
+	// signal running handlers to stop
+	cancel()
+
+	<-listening
+
+	// stop the typing timers, so that they don't dispatch events while
+	// waiting
+	if h.s.typing != nil {
+		h.s.typing.close()
+	}
+
+	h.wg.Wait()
+}
+
+// Restart replaces the dispatch pipeline of an opened EventHandler with a
+// fresh one, without affecting the gateway connection.
+//
+// It stops reading events from the gateway, dispatches the events that are
+// still buffered by the event queue or the FaultInjection, and waits for all
+// handlers to finish.
+// It then resets the tracking of unavailable guilds, the typing sessions, the
+// deferred guild caching jobs, and the dropped events counter, and resumes
+// reading events.
+// If the Metrics have a Reset method, as Stats do, it will be called as well.
+// Note that guilds that were unavailable before the restart, will be reported
+// through GuildJoinEvents, once they become available.
+//
+// Handlers and middlewares are kept.
+// Changes to the fields of the EventHandler, such as MaxConcurrentHandlers,
+// take effect.
+//
+// Events received from the gateway during the restart are dispatched once
+// the restart completed.
+// Events that the event queue drops, because it is full, and events dropped
+// by the FaultInjection are lost, as usual.
+// If the EventHandler isn't open, Restart is a no-op.
+//
+// Open, Close and Restart are serialized, so that Close waits for a Restart
+// in progress, and a closed EventHandler is never reopened by Restart.
+func (h *EventHandler) Restart() {
+	h.lifecycleMutex.Lock()
+	defer h.lifecycleMutex.Unlock()
+
+	h.openMutex.RLock()
+	events, stop, listening, open := h.events, h.stopIntake, h.listening, h.closer != nil
+	h.openMutex.RUnlock()
+
+	if !open {
+		return
+	}
+
+	close(stop)
+	<-listening // all buffered events were dispatched
+
+	if h.s.guildCache != nil {
+		h.s.guildCache.reset()
+	}
+
+	h.stopListening()
+
+	h.s.unavailableGuilds.Clear()
+	h.s.unreadyGuilds.Clear()
+	h.s.chunkedGuilds.Clear()
+
+	atomic.StoreUint64(&h.dropped, 0)
+
+	if r, ok := h.Metrics.(interface{ Reset() }); ok {
+		r.Reset()
+	}
+
+	h.startListening(events)
+}
+
+// dispatch calls the passed event in a new goroutine.
+func (h *EventHandler) dispatch(e interface{}) {
+	h.wg.Add(1)
+	h.dispatchAdded(e)
+}
+
+// dispatchAdded calls the passed event in a new goroutine.
+// The caller must have already added the event to the WaitGroup of the
+// EventHandler.
+func (h *EventHandler) dispatchAdded(e interface{}) {
+	go func() {
+		h.Call(e)
+		h.wg.Done()
+	}()
+}
+
+// run runs f in a new goroutine.
+// If the number of concurrently executing handlers is limited, run blocks
+// until a slot is available, before starting the goroutine.
+func (h *EventHandler) run(f func()) {
+	h.openMutex.RLock()
+	slots := h.handlerSlots
+	h.openMutex.RUnlock()
+
+	if slots == nil {
+		go f()
+		return
+	}
+
+	slots <- struct{}{}
+
+	go func() {
+		defer func() { <-slots }()
+		f()
+	}()
+}
+
+// addInternalHandler adds a handler that is called by the event listener
+// with every gateway event, after the state was updated with it, and before
+// the event is dispatched.
+// It returns a function that removes the handler.
+//
+// Internal handlers bypass all middlewares and the MaxConcurrentHandlers
+// limit, so that the State can wait for gateway events, regardless of how
+// the EventHandler is configured.
+// Since they are called by the event listener, they must not block.
+func (h *EventHandler) addInternalHandler(f func(gatewayEvent interface{})) (rm func()) {
+	h.internalHandlersMutex.Lock()
+	defer h.internalHandlersMutex.Unlock()
+
+	serial := h.internalHandlersSerial
+	h.internalHandlersSerial++
+
+	h.internalHandlers[serial] = f
+
+	return func() {
+		h.internalHandlersMutex.Lock()
+		delete(h.internalHandlers, serial)
+		h.internalHandlersMutex.Unlock()
+	}
+}
+
+// callInternalHandlers calls the internal handlers with the passed gateway
+// event.
+func (h *EventHandler) callInternalHandlers(gatewayEvent interface{}) {
+	h.internalHandlersMutex.RLock()
+	defer h.internalHandlersMutex.RUnlock()
+
+	for _, f := range h.internalHandlers {
+		f(gatewayEvent)
 	}
 }
 
@@ -173,8 +746,9 @@ var (
 //
 // Middlewares must be of the same type as the handlers or must be an
 // interface{} or Base handlers.
+// Instead of a single middleware, a MiddlewareChain may be passed.
 func (h *EventHandler) AddHandler(handler interface{}, middlewares ...interface{}) (rm func(), err error) {
-	return h.addHandler(handler, false, middlewares...)
+	return h.addHandler(handler, false, HandlerOptions{}, middlewares...)
 }
 
 // MustAddHandler is the same as AddHandler, but panics if AddHandler returns
@@ -192,7 +766,7 @@ func (h *EventHandler) MustAddHandler(handler interface{}, middlewares ...interf
 // If middlewares prevent execution, the handler will be executed on the next
 // event.
 func (h *EventHandler) AddHandlerOnce(handler interface{}, middlewares ...interface{}) error {
-	_, err := h.addHandler(handler, true, middlewares...)
+	_, err := h.addHandler(handler, true, HandlerOptions{}, middlewares...)
 	return err
 }
 
@@ -205,29 +779,100 @@ func (h *EventHandler) MustAddHandlerOnce(handler interface{}, middlewares ...in
 	}
 }
 
+// AddHandlerWithOptions is the same as AddHandler, but additionally allows
+// specifying HandlerOptions.
+func (h *EventHandler) AddHandlerWithOptions(
+	handler interface{}, o HandlerOptions, middlewares ...interface{},
+) (rm func(), err error) {
+	return h.addHandler(handler, false, o, middlewares...)
+}
+
+// MustAddHandlerWithOptions is the same as AddHandlerWithOptions, but panics
+// if AddHandlerWithOptions returns an error.
+func (h *EventHandler) MustAddHandlerWithOptions(
+	handler interface{}, o HandlerOptions, middlewares ...interface{},
+) func() {
+	rm, err := h.AddHandlerWithOptions(handler, o, middlewares...)
+	if err != nil {
+		panic(err)
+	}
+
+	return rm
+}
+
+// AddHandlerIf is the same as AddHandler, but the handler is only called for
+// events that the passed predicate returns true for.
+//
+// The predicate is called before the event is copied and before the
+// middlewares are called, and should therefore be cheap.
+// It receives the original event, and must not modify it.
+func (h *EventHandler) AddHandlerIf(
+	pred func(e interface{}) bool, handler interface{}, middlewares ...interface{},
+) (rm func(), err error) {
+	return h.addHandler(handler, false, HandlerOptions{Predicate: pred}, middlewares...)
+}
+
+// MustAddHandlerIf is the same as AddHandlerIf, but panics if AddHandlerIf
+// returns an error.
+func (h *EventHandler) MustAddHandlerIf(
+	pred func(e interface{}) bool, handler interface{}, middlewares ...interface{},
+) func() {
+	rm, err := h.AddHandlerIf(pred, handler, middlewares...)
+	if err != nil {
+		panic(err)
+	}
+
+	return rm
+}
+
 // AutoAddHandlers adds all handlers methods of the passed struct to the
 // EventHandler.
 // scan must be a pointer to a struct.
+//
+// Methods that aren't valid handlers are silently skipped.
+// Use TryAutoAddHandlers to find out which methods were added.
 func (h *EventHandler) AutoAddHandlers(scan interface{}, middlewares ...interface{}) {
+	_, _ = h.TryAutoAddHandlers(scan, middlewares...)
+}
+
+// TryAutoAddHandlers is the same as AutoAddHandlers, but returns the names of
+// the methods that were added as handlers.
+//
+// If methods were skipped, because they aren't valid handlers or the
+// middlewares don't match them, an *AutoAddError listing them will be
+// returned.
+// If scan is not a pointer, ErrInvalidAutoAdd will be returned.
+func (h *EventHandler) TryAutoAddHandlers(
+	scan interface{}, middlewares ...interface{},
+) (registered []string, err error) {
 	v := reflect.ValueOf(scan)
 
 	if v.Kind() != reflect.Ptr {
-		return
+		return nil, ErrInvalidAutoAdd
 	}
 
+	var skipped []SkippedHandler
+
 	for i := 0; i < v.NumMethod(); i++ {
-		m := v.Method(i)
+		name := v.Type().Method(i).Name
 
-		if m.CanInterface() {
-			// just try, AddHandler will abort if m is not a valid
-			// handler func
-			_, _ = h.AddHandler(m.Interface(), middlewares...)
+		// just try, AddHandler will abort if m is not a valid handler func
+		if _, err := h.AddHandler(v.Method(i).Interface(), middlewares...); err != nil {
+			skipped = append(skipped, SkippedHandler{Name: name, Err: err})
+		} else {
+			registered = append(registered, name)
 		}
 	}
+
+	if len(skipped) > 0 {
+		return registered, &AutoAddError{Skipped: skipped}
+	}
+
+	return registered, nil
 }
 
 func (h *EventHandler) addHandler(
-	handler interface{}, execOnce bool, middlewares ...interface{},
+	handler interface{}, execOnce bool, o HandlerOptions, middlewares ...interface{},
 ) (rm func(), err error) {
 	handlerVal := reflect.ValueOf(handler)
 	handlerType := handlerVal.Type()
@@ -252,11 +897,13 @@ func (h *EventHandler) addHandler(
 	}
 
 	gh := &genericHandler{
-		handler: handlerVal,
-		channel: handlerType.Kind() == reflect.Chan,
+		handler:   handlerVal,
+		channel:   handlerType.Kind() == reflect.Chan,
+		priority:  o.Priority,
+		predicate: o.Predicate,
 	}
 
-	gh.middlewares, err = h.extractMiddlewares(middlewares, eventType)
+	gh.middlewares, gh.enrich, err = h.extractMiddlewares(middlewares, eventType)
 	if err != nil {
 		return nil, err
 	}
@@ -268,11 +915,11 @@ func (h *EventHandler) addHandler(
 			h.handlersMutex.Lock()
 			defer h.handlersMutex.Unlock()
 
-			handler := h.handlers[handlerType]
+			handler := h.handlers[eventType]
 
 			for i, ha := range handler {
 				if ha == gh {
-					h.handlers[handlerType] = append(handler[:i], handler[i+1:]...)
+					h.handlers[eventType] = append(handler[:i], handler[i+1:]...)
 					break
 				}
 			}
@@ -291,23 +938,34 @@ func (h *EventHandler) addHandler(
 	return rm, nil
 }
 
-func (h *EventHandler) extractMiddlewares(raw []interface{}, eventType reflect.Type) ([]middleware, error) {
-	mw := make([]middleware, len(raw))
+// extractMiddlewares validates the passed middlewares for a handler of the
+// passed event type.
+// enrich reports whether one of the middlewares is an Enricher.
+func (h *EventHandler) extractMiddlewares(
+	raw []interface{}, eventType reflect.Type,
+) (mw []middleware, enrich bool, err error) {
+	raw = flattenMiddlewares(raw)
+	mw = make([]middleware, len(raw))
 
 	for i, m := range raw {
+		if e, ok := m.(Enricher); ok {
+			m = e.Middleware
+			enrich = true
+		}
+
 		mv := reflect.ValueOf(m)
 		mt := mv.Type()
 
 		if mt.Kind() != reflect.Func {
-			return nil, ErrInvalidMiddleware
+			return nil, false, ErrInvalidMiddleware
 		}
 
 		// we expect two input params, first must be state
 		if mt.NumIn() != 2 || mt.In(0) != stateType {
-			return nil, ErrInvalidMiddleware
+			return nil, false, ErrInvalidMiddleware
 			// we expect either no return or an error return
 		} else if mt.NumOut() != 0 && (mt.NumOut() != 1 || mt.Out(0) != errorType) {
-			return nil, ErrInvalidMiddleware
+			return nil, false, ErrInvalidMiddleware
 		}
 
 		switch met := mt.In(1); met {
@@ -317,11 +975,11 @@ func (h *EventHandler) extractMiddlewares(raw []interface{}, eventType reflect.T
 				typ:        met,
 			}
 		default:
-			return nil, ErrInvalidMiddleware
+			return nil, false, ErrInvalidMiddleware
 		}
 	}
 
-	return mw, nil
+	return mw, enrich, nil
 }
 
 // AddMiddleware adds the passed middleware as a global middleware.
@@ -330,15 +988,33 @@ func (h *EventHandler) extractMiddlewares(raw []interface{}, eventType reflect.T
 // pointer to an event, *Base or interface{}.
 // Optionally, a middleware may return an error.
 func (h *EventHandler) AddMiddleware(f interface{}) error {
+	_, err := h.AddMiddlewareRemovable(f)
+	return err
+}
+
+// MustAddMiddleware is the same as AddMiddleware but panics if AddMiddleware
+// returns an error.
+func (h *EventHandler) MustAddMiddleware(f interface{}) {
+	err := h.AddMiddleware(f)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// AddMiddlewareRemovable is the same as AddMiddleware, but additionally
+// returns a function that removes the middleware, when called.
+// Events that are already being dispatched, when the middleware is removed,
+// may still be passed to it.
+func (h *EventHandler) AddMiddlewareRemovable(f interface{}) (rm func(), err error) {
 	fv := reflect.ValueOf(f)
 	ft := fv.Type()
 
 	// we expect two input params, first must be state
 	if ft.NumIn() != 2 || ft.In(0) != stateType {
-		return ErrInvalidMiddleware
+		return nil, ErrInvalidMiddleware
 		// we expect either no return or an error return
 	} else if ft.NumOut() != 0 && (ft.NumOut() != 1 || ft.Out(0) != errorType) {
-		return ErrInvalidMiddleware
+		return nil, ErrInvalidMiddleware
 	}
 
 	et := ft.In(1)
@@ -346,33 +1022,107 @@ func (h *EventHandler) AddMiddleware(f interface{}) error {
 	h.globalMiddlewaresMutex.Lock()
 	defer h.globalMiddlewaresMutex.Unlock()
 
+	serial := h.currentSerial
+	h.currentSerial++
+
 	h.globalMiddlewares[et] = append(h.globalMiddlewares[et], globalMiddleware{
 		middleware: fv,
-		serial:     h.currentSerial,
+		serial:     serial,
 	})
 
-	h.currentSerial++
+	var once sync.Once
+
+	rm = func() {
+		once.Do(func() {
+			h.globalMiddlewaresMutex.Lock()
+			defer h.globalMiddlewaresMutex.Unlock()
+
+			middlewares := h.globalMiddlewares[et]
+
+			for i, m := range middlewares {
+				if m.serial == serial {
+					// don't modify the backing array, as it may be in use by
+					// callGlobalMiddlewares
+					cp := make([]globalMiddleware, 0, len(middlewares)-1)
+					cp = append(cp, middlewares[:i]...)
+					h.globalMiddlewares[et] = append(cp, middlewares[i+1:]...)
 
-	return nil
+					break
+				}
+			}
+		})
+	}
+
+	return rm, nil
 }
 
-// MustAddMiddleware is the same as AddMiddleware but panics if AddMiddleware
-// returns an error.
-func (h *EventHandler) MustAddMiddleware(f interface{}) {
-	err := h.AddMiddleware(f)
+// MustAddMiddlewareRemovable is the same as AddMiddlewareRemovable but panics
+// if AddMiddlewareRemovable returns an error.
+func (h *EventHandler) MustAddMiddlewareRemovable(f interface{}) (rm func()) {
+	rm, err := h.AddMiddlewareRemovable(f)
 	if err != nil {
 		panic(err)
 	}
+
+	return rm
+}
+
+// AddAfterMiddleware adds the passed AfterMiddleware.
+// AfterMiddlewares are called in the order they were added, every time a
+// handler func returns, including those that returned an error.
+//
+// They will not be called for channel handlers, handlers whose middlewares
+// blocked execution, or handlers that panicked.
+func (h *EventHandler) AddAfterMiddleware(m AfterMiddleware) {
+	h.afterMiddlewaresMutex.Lock()
+	h.afterMiddlewares = append(h.afterMiddlewares, m)
+	h.afterMiddlewaresMutex.Unlock()
 }
 
 // Call can be used to manually dispatch an event.
 // For this to succeed, e must be a pointer to an event, and it's Base field
 // must be set.
 func (h *EventHandler) Call(e interface{}) {
+	if h.OnDispatchStart != nil {
+		h.OnDispatchStart(e)
+	}
+
+	var done *sync.WaitGroup
+
+	b := h.newEventBudget()
+	cancel := h.setEventContext(e)
+
+	if h.OnDispatchEnd != nil || cancel != nil {
+		done = new(sync.WaitGroup)
+		start := time.Now()
+
+		defer func() {
+			h.wg.Add(1)
+
+			go func() {
+				done.Wait()
+
+				if cancel != nil {
+					cancel()
+				}
+
+				if h.OnDispatchEnd != nil {
+					h.OnDispatchEnd(e, time.Since(start))
+				}
+
+				h.wg.Done()
+			}()
+		}()
+	}
+
 	ev := reflect.ValueOf(e)
 	et := reflect.TypeOf(e)
 
 	abort := h.callGlobalMiddlewares(ev, et)
+	if !abort {
+		h.reportLag(e)
+	}
+
 	ev = ev.Elem() // from now functions only take elem
 	direct := false
 
@@ -382,63 +1132,230 @@ func (h *EventHandler) Call(e interface{}) {
 	case *GuildCreateEvent:
 		specificEvent := h.handleGuildCreate(e)
 		if !abort {
-			sev := reflect.ValueOf(specificEvent)
+			sev := reflect.ValueOf(specificEvent).Elem()
 			set := reflect.TypeOf(specificEvent)
-			h.call(sev, set, false)
+			h.call(sev, set, false, done, b)
 		}
 
 		direct = true
 	case *GuildDeleteEvent:
 		specificEvent := h.handleGuildDelete(e)
 		if !abort {
-			sev := reflect.ValueOf(specificEvent)
+			sev := reflect.ValueOf(specificEvent).Elem()
 			set := reflect.TypeOf(specificEvent)
-			h.call(sev, set, false)
+			h.call(sev, set, false, done, b)
 		}
 
 		direct = true
 	}
 
 	if !abort {
-		h.call(ev, et, direct)
+		h.call(ev, et, direct, done, b)
 	}
 }
 
+// setEventContext sets the context of the Base of the passed event.
+// The context is canceled, once the EventHandler is closed, or the
+// HandlerTimeout expires.
+//
+// If a context was already set, it is used as parent, instead of the context
+// of the EventHandler.
+//
+// The returned context.CancelFunc must be called once all handlers of the
+// event have finished.
+// It is nil, if there is nothing to cancel.
+func (h *EventHandler) setEventContext(e interface{}) context.CancelFunc {
+	b, ok := e.(interface{ base() *Base })
+	if !ok || b.base() == nil {
+		return nil
+	}
+
+	base := b.base()
+
+	parent, ok := base.lookupContext()
+	if !ok {
+		h.openMutex.RLock()
+		parent = h.ctx
+		h.openMutex.RUnlock()
+
+		if parent == nil { // not opened
+			if h.HandlerTimeout <= 0 {
+				return nil
+			}
+
+			parent = context.Background()
+		}
+	}
+
+	if h.HandlerTimeout <= 0 {
+		base.SetContext(parent)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(parent, h.HandlerTimeout)
+	base.SetContext(ctx)
+
+	return cancel
+}
+
 // call calls the handlers for the passed typed using the event wrapped in ev.
 // ev must not be a pointer, however, et is expected to be the pointerized type
 // of ev.
 //
 // direct specifies, whether or not interface and Base handlers should be
 // called for the event as well.
-func (h *EventHandler) call(ev reflect.Value, et reflect.Type, direct bool) {
+//
+// If done is not nil, it will be used to signal when all handlers have
+// finished executing.
+//
+// b is the eventBudget of the event, or nil, if there is none.
+func (h *EventHandler) call(
+	ev reflect.Value, et reflect.Type, direct bool, done *sync.WaitGroup, b *eventBudget,
+) {
 	h.handlersMutex.RLock()
-	defer h.handlersMutex.RUnlock()
 
+	// copy, so that we don't share the backing array with h.handlers, which
+	// may get modified by the remove funcs
+	var handlers []*genericHandler
 	if !direct {
-		h.callHandlers(ev, et, h.handlers[interfaceType])
-		h.callHandlers(ev, et, h.handlers[baseType])
+		handlers = append(handlers, h.handlers[interfaceType]...)
+		handlers = append(handlers, h.handlers[baseType]...)
+	}
+
+	handlers = append(handlers, h.handlers[et]...)
+
+	h.handlersMutex.RUnlock()
+
+	if h.Metrics != nil {
+		h.Metrics.EventDispatched(ev.Type().Name(), ev.Addr().Interface())
+	}
+
+	if done != nil {
+		done.Add(1)
+	}
+
+	groups := groupByPriority(handlers)
+	if len(groups) <= 1 {
+		if done == nil {
+			h.callHandlers(ev, et, handlers, nil, b)
+			return
+		}
+
+		var wg sync.WaitGroup
+		h.callHandlers(ev, et, handlers, &wg, b)
+
+		h.wg.Add(1)
+
+		go func() {
+			wg.Wait()
+			done.Done()
+			h.wg.Done()
+		}()
+
+		return
+	}
+
+	h.wg.Add(1)
+
+	go func() {
+		defer h.wg.Done()
+
+		if done != nil {
+			defer done.Done()
+		}
+
+		for _, g := range groups {
+			var wg sync.WaitGroup
+			h.callHandlers(ev, et, g, &wg, b)
+			wg.Wait()
+		}
+	}()
+}
+
+// groupByPriority groups the passed handlers by their priority, sorting the
+// groups by descending priority.
+// The order of handlers within a group is preserved.
+func groupByPriority(handlers []*genericHandler) [][]*genericHandler {
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	sorted := true
+
+	for _, gh := range handlers[1:] {
+		if gh.priority != handlers[0].priority {
+			sorted = false
+			break
+		}
+	}
+
+	if sorted { // fast path, all priorities are equal
+		return [][]*genericHandler{handlers}
+	}
+
+	sort.SliceStable(handlers, func(i, j int) bool {
+		return handlers[i].priority > handlers[j].priority
+	})
+
+	var groups [][]*genericHandler
+
+	start := 0
+
+	for i := 1; i <= len(handlers); i++ {
+		if i == len(handlers) || handlers[i].priority != handlers[start].priority {
+			groups = append(groups, handlers[start:i])
+			start = i
+		}
 	}
 
-	h.callHandlers(ev, et, h.handlers[et])
+	return groups
 }
 
 // callHandlers calls the passed slice of handlers using the passed event ev.
 // ev must not be a pointer, however, et is expected to be the pointerized type
 // of ev.
-func (h *EventHandler) callHandlers(ev reflect.Value, et reflect.Type, handlers []*genericHandler) {
+//
+// If wg is not nil, it will be used to signal when all handlers have finished
+// executing, in addition to the EventHandler's own WaitGroup.
+//
+// b is the eventBudget of the event, or nil, if there is none.
+func (h *EventHandler) callHandlers(
+	ev reflect.Value, et reflect.Type, handlers []*genericHandler, wg *sync.WaitGroup, b *eventBudget,
+) {
 	h.wg.Add(len(handlers))
 
+	if wg != nil {
+		wg.Add(len(handlers))
+	}
+
 	for _, gh := range handlers {
-		go func(gh *genericHandler) {
+		gh := gh
+
+		h.run(func() {
 			defer h.wg.Done()
 
+			if wg != nil {
+				defer wg.Done()
+			}
+
 			defer func() {
 				if rec := recover(); rec != nil {
-					h.PanicHandler(rec)
+					h.handlePanic(rec, ev.Addr())
 				}
 			}()
 
+			if h.checkBudget(b, ev.Addr().Interface(), funcName(gh.handler)) {
+				return
+			}
+
+			if gh.predicate != nil && !gh.predicate(ev.Addr().Interface()) {
+				return
+			}
+
 			cp := copyEvent(ev, et)
+			if gh.enrich {
+				isolateEvent(cp)
+			}
 
 			if h.callMiddlewares(cp, et, gh.middlewares) {
 				return
@@ -446,22 +1363,66 @@ func (h *EventHandler) callHandlers(ev reflect.Value, et reflect.Type, handlers
 
 			if gh.once != nil {
 				gh.once.Do(func() {
-					h.callHandler(gh, cp)
+					h.callHandler(gh, cp, b)
 					gh.rm()
 				})
 			} else {
-				h.callHandler(gh, cp)
+				h.callHandler(gh, cp, b)
 			}
-		}(gh)
+		})
 	}
 }
 
-func (h *EventHandler) callHandler(gh *genericHandler, ev reflect.Value) {
+func (h *EventHandler) callHandler(gh *genericHandler, ev reflect.Value, b *eventBudget) {
 	if gh.channel {
 		gh.handler.TrySend(ev)
+		return
+	}
+
+	if h.SlowHandlerThreshold > 0 && h.SlowHandler != nil {
+		t := time.AfterFunc(h.SlowHandlerThreshold, func() {
+			h.SlowHandler(ev.Interface(), funcName(gh.handler))
+		})
+		defer t.Stop()
+	}
+
+	start := time.Now()
+	result := gh.handler.Call([]reflect.Value{h.sv, ev})
+	d := time.Since(start)
+
+	err := resultError(result)
+
+	h.checkBudget(b, ev.Interface(), funcName(gh.handler))
+
+	if h.Metrics != nil {
+		h.Metrics.HandlerExecuted(ev.Elem().Type().Name(), funcName(gh.handler), ev.Interface(), d, err)
+	}
+
+	h.handleResult(result, ev, gh.handler)
+	h.callAfterMiddlewares(ev.Interface(), err, d)
+}
+
+// handlePanic handles the passed recovered value, that was produced while
+// handling the event ev.
+// ev must be a pointer to the event.
+// handlePanic must be called from the deferred func that recovered.
+func (h *EventHandler) handlePanic(rec interface{}, ev reflect.Value) {
+	if h.PanicHandlerFull != nil {
+		h.PanicHandlerFull(rec, debug.Stack(), ev.Interface())
 	} else {
-		result := gh.handler.Call([]reflect.Value{h.sv, ev})
-		h.handleResult(result)
+		h.PanicHandler(rec)
+	}
+}
+
+// callAfterMiddlewares calls the AfterMiddlewares using the passed event,
+// error and duration.
+func (h *EventHandler) callAfterMiddlewares(e interface{}, err error, d time.Duration) {
+	h.afterMiddlewaresMutex.RLock()
+	afterMiddlewares := h.afterMiddlewares
+	h.afterMiddlewaresMutex.RUnlock()
+
+	for _, m := range afterMiddlewares {
+		m(h.s, e, err, d)
 	}
 }
 
@@ -527,7 +1488,7 @@ func (h *EventHandler) callGlobalMiddlewares(ev reflect.Value, et reflect.Type)
 		func() {
 			defer func() {
 				if rec := recover(); rec != nil {
-					h.PanicHandler(rec)
+					h.handlePanic(rec, ev)
 					didPanic = true
 				}
 			}()
@@ -539,7 +1500,7 @@ func (h *EventHandler) callGlobalMiddlewares(ev reflect.Value, et reflect.Type)
 			return true
 		}
 
-		if h.handleResult(result) {
+		if h.handleResult(result, ev, next.middleware) {
 			return true
 		}
 
@@ -574,7 +1535,7 @@ func (h *EventHandler) callMiddlewares(ev reflect.Value, et reflect.Type, middle
 			continue
 		}
 
-		if h.handleResult(result) {
+		if h.handleResult(result, ev, m.middleware) {
 			return true
 		}
 	}
@@ -583,6 +1544,8 @@ func (h *EventHandler) callMiddlewares(ev reflect.Value, et reflect.Type, middle
 }
 
 func (h *EventHandler) handleReady(e *ReadyEvent) {
+	atomic.StoreUint32(&h.ready, 1)
+
 	for _, g := range e.Guilds {
 		// store this so we know when we need to dispatch the corresponding
 		// GuildReadyEvent
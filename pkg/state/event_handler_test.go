@@ -0,0 +1,369 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventHandler_Restart(t *testing.T) {
+	t.Run("not open", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		s.Restart()
+
+		s.openMutex.RLock()
+		defer s.openMutex.RUnlock()
+
+		assert.Nil(t, s.closer)
+	})
+
+	t.Run("buffered events", func(t *testing.T) {
+		_, s := NewMocker(t)
+		s.QueueSize = 100
+
+		var received int32
+
+		s.MustAddHandler(func(_ *State, _ *MessageCreateEvent) {
+			atomic.AddInt32(&received, 1)
+		})
+
+		events := make(chan interface{}, 50)
+		s.EventHandler.Open(events)
+
+		defer s.EventHandler.Close()
+
+		for i := 1; i <= cap(events); i++ {
+			events <- &gateway.MessageCreateEvent{
+				Message: discord.Message{ID: discord.MessageID(i), ChannelID: 1},
+			}
+		}
+
+		s.Restart()
+
+		assert.Eventually(t, func() bool {
+			return atomic.LoadInt32(&received) == int32(cap(events))
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("close while restarting", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		// delay the event, so that Restart is still waiting for it to be
+		// dispatched, when Close is called
+		s.FaultInjection = &FaultInjection{DelayProbability: 1, MaxDelay: 100 * time.Millisecond, Seed: 1}
+
+		events := make(chan interface{}, 1)
+		s.EventHandler.Open(events)
+
+		events <- &gateway.MessageCreateEvent{Message: discord.Message{ID: 1, ChannelID: 1}}
+
+		restarted := make(chan struct{})
+
+		go func() {
+			s.Restart()
+			close(restarted)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+
+		s.EventHandler.Close()
+		<-restarted
+
+		s.openMutex.RLock()
+		closer := s.closer
+		s.openMutex.RUnlock()
+
+		assert.Nil(t, closer, "Restart reopened the closed EventHandler")
+	})
+}
+
+func TestEventHandler_AddHandlerWithOptions(t *testing.T) {
+	t.Run("priority", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		var (
+			order []int
+			mutex sync.Mutex
+		)
+
+		add := func(priority int, d time.Duration) {
+			s.MustAddHandlerWithOptions(func(*State, *MessageCreateEvent) {
+				time.Sleep(d)
+
+				mutex.Lock()
+				order = append(order, priority)
+				mutex.Unlock()
+			}, HandlerOptions{Priority: priority})
+		}
+
+		// handlers with a higher priority take longer, so that they would
+		// finish last, if they weren't awaited
+		add(-1, 0)
+		add(0, 10*time.Millisecond)
+		add(1, 20*time.Millisecond)
+		add(0, 10*time.Millisecond)
+
+		s.Call(newMessageCreateEvent())
+		s.wg.Wait()
+
+		assert.Equal(t, []int{1, 0, 0, -1}, order)
+	})
+}
+
+func TestGroupByPriority(t *testing.T) {
+	a := &genericHandler{priority: 0}
+	b := &genericHandler{priority: 2}
+	c := &genericHandler{priority: 0}
+	d := &genericHandler{priority: -1}
+
+	groups := groupByPriority([]*genericHandler{a, b, c, d})
+
+	// order within a group is preserved
+	expect := [][]*genericHandler{{b}, {a, c}, {d}}
+	assert.Equal(t, expect, groups)
+
+	assert.Nil(t, groupByPriority(nil))
+}
+
+func TestEventHandler_MaxConcurrentHandlers(t *testing.T) {
+	_, s := NewMocker(t)
+	s.MaxConcurrentHandlers = 2
+
+	var running, maxRunning, done int32
+
+	s.MustAddHandler(func(*State, *MessageCreateEvent) {
+		n := atomic.AddInt32(&running, 1)
+
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&done, 1)
+	})
+
+	events := make(chan interface{})
+	s.EventHandler.Open(events)
+
+	defer s.EventHandler.Close()
+
+	for i := 1; i <= 10; i++ {
+		events <- &gateway.MessageCreateEvent{
+			Message: discord.Message{ID: discord.MessageID(i), ChannelID: 1},
+		}
+	}
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&done) == 10
+	}, time.Second, time.Millisecond)
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(2))
+}
+
+func TestEventHandler_AddAfterMiddleware(t *testing.T) {
+	_, s := NewMocker(t)
+
+	handlerErr := errors.New("abc")
+
+	s.ErrorHandler = func(error) {}
+	s.MustAddHandler(func(*State, *MessageCreateEvent) error { return handlerErr })
+
+	var (
+		calls []error
+		mutex sync.Mutex
+	)
+
+	s.AddAfterMiddleware(func(_ *State, e interface{}, err error, _ time.Duration) {
+		assert.IsType(t, new(MessageCreateEvent), e)
+
+		mutex.Lock()
+		calls = append(calls, err)
+		mutex.Unlock()
+	})
+
+	// not called for handlers blocked by their middlewares
+	s.MustAddHandler(func(*State, *MessageCreateEvent) {},
+		func(*State, *MessageCreateEvent) error { return Filtered })
+
+	s.Call(newMessageCreateEvent())
+	s.wg.Wait()
+
+	assert.Equal(t, []error{handlerErr}, calls)
+}
+
+func TestEventHandler_EventBudget(t *testing.T) {
+	_, s := NewMocker(t)
+	s.EventBudget = 10 * time.Millisecond
+
+	var budgetCalls, skippedCalls int32
+
+	s.BudgetHandler = func(_ interface{}, _ string, spent time.Duration) {
+		atomic.AddInt32(&budgetCalls, 1)
+		assert.Greater(t, int64(spent), int64(s.EventBudget))
+	}
+
+	s.MustAddHandlerWithOptions(func(*State, *MessageCreateEvent) {
+		time.Sleep(20 * time.Millisecond)
+	}, HandlerOptions{Priority: 1})
+
+	for i := 0; i < 2; i++ {
+		s.MustAddHandler(func(*State, *MessageCreateEvent) {
+			atomic.AddInt32(&skippedCalls, 1)
+		})
+	}
+
+	s.Call(newMessageCreateEvent())
+	s.wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&budgetCalls))
+	assert.Zero(t, atomic.LoadInt32(&skippedCalls))
+}
+
+func TestEventHandler_SlowHandler(t *testing.T) {
+	_, s := NewMocker(t)
+	s.SlowHandlerThreshold = 10 * time.Millisecond
+
+	slow := make(chan string, 2)
+	s.SlowHandler = func(_ interface{}, handlerName string) { slow <- handlerName }
+
+	release := make(chan struct{})
+
+	s.MustAddHandler(func(*State, *MessageCreateEvent) { <-release })
+	s.MustAddHandler(func(*State, *MessageCreateEvent) {})
+
+	s.Call(newMessageCreateEvent())
+
+	// reported while the handler is still executing
+	select {
+	case name := <-slow:
+		assert.Contains(t, name, "TestEventHandler_SlowHandler")
+	case <-time.After(time.Second):
+		t.Fatal("SlowHandler wasn't called")
+	}
+
+	close(release)
+	s.wg.Wait()
+
+	assert.Len(t, slow, 0, "fast handler was reported as slow")
+}
+
+func TestEventHandler_OnFiltered(t *testing.T) {
+	_, s := NewMocker(t)
+
+	s.ErrorHandler = func(err error) { t.Errorf("filtered event reported as error: %v", err) }
+
+	var (
+		reasons []string
+		mutex   sync.Mutex
+	)
+
+	s.OnFiltered = func(e interface{}, filterName, reason string) {
+		assert.IsType(t, new(MessageCreateEvent), e)
+		assert.NotEmpty(t, filterName)
+
+		mutex.Lock()
+		reasons = append(reasons, reason)
+		mutex.Unlock()
+	}
+
+	s.MustAddHandler(func(*State, *MessageCreateEvent) {
+		t.Error("filtered handler was called")
+	}, func(*State, *MessageCreateEvent) error {
+		return &FilterError{Reason: "abc"}
+	})
+
+	s.MustAddHandler(func(*State, *MessageCreateEvent) error {
+		return Filtered
+	})
+
+	s.Call(newMessageCreateEvent())
+	s.wg.Wait()
+
+	assert.ElementsMatch(t, []string{"abc", ""}, reasons)
+	assert.True(t, errors.Is(&FilterError{Reason: "abc"}, Filtered))
+}
+
+func TestEventHandler_AddMiddlewareRemovable(t *testing.T) {
+	_, s := NewMocker(t)
+
+	var aCalls, bCalls int32
+
+	rm := s.MustAddMiddlewareRemovable(func(*State, interface{}) { atomic.AddInt32(&aCalls, 1) })
+	s.MustAddMiddleware(func(*State, *MessageCreateEvent) { atomic.AddInt32(&bCalls, 1) })
+
+	s.Call(newMessageCreateEvent())
+	s.wg.Wait()
+
+	rm()
+	rm() // removing twice is a no-op
+
+	s.Call(newMessageCreateEvent())
+	s.wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&aCalls))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&bCalls))
+}
+
+func TestEventHandler_AddHandlerIf(t *testing.T) {
+	_, s := NewMocker(t)
+
+	var middlewareCalls int32
+
+	received := make(chan discord.ChannelID, 2)
+
+	s.MustAddHandlerIf(func(e interface{}) bool {
+		return e.(*MessageCreateEvent).ChannelID == 1
+	}, func(_ *State, e *MessageCreateEvent) {
+		received <- e.ChannelID
+	}, func(*State, *MessageCreateEvent) {
+		atomic.AddInt32(&middlewareCalls, 1)
+	})
+
+	s.Call(newMessageCreateEvent())
+
+	e := newMessageCreateEvent()
+	e.ChannelID = 2
+	s.Call(e)
+
+	s.wg.Wait()
+
+	require.Len(t, received, 1)
+	assert.Equal(t, discord.ChannelID(1), <-received)
+	// the predicate is checked before the middlewares are called
+	assert.Equal(t, int32(1), atomic.LoadInt32(&middlewareCalls))
+}
+
+func TestEventHandler_HandlerTimeout(t *testing.T) {
+	_, s := NewMocker(t)
+	s.HandlerTimeout = 10 * time.Millisecond
+
+	errs := make(chan error, 1)
+
+	s.MustAddHandler(func(_ *State, e *MessageCreateEvent) {
+		select {
+		case <-e.Context().Done():
+			errs <- e.Context().Err()
+		case <-time.After(time.Second):
+			errs <- nil
+		}
+	})
+
+	s.Call(newMessageCreateEvent())
+	s.wg.Wait()
+
+	assert.Equal(t, context.DeadlineExceeded, <-errs)
+}
@@ -0,0 +1,100 @@
+package state
+
+import "reflect"
+
+var (
+	// eventNames maps the types of events to their names.
+	eventNames = map[reflect.Type]string{
+		reflect.TypeOf(new(ChannelCreateEvent)):              "CHANNEL_CREATE",
+		reflect.TypeOf(new(ChannelUpdateEvent)):              "CHANNEL_UPDATE",
+		reflect.TypeOf(new(ChannelDeleteEvent)):              "CHANNEL_DELETE",
+		reflect.TypeOf(new(ChannelPinsUpdateEvent)):          "CHANNEL_PINS_UPDATE",
+		reflect.TypeOf(new(ChannelUnreadUpdateEvent)):        "CHANNEL_UNREAD_UPDATE",
+		reflect.TypeOf(new(GuildCreateEvent)):                "GUILD_CREATE",
+		reflect.TypeOf(new(GuildUpdateEvent)):                "GUILD_UPDATE",
+		reflect.TypeOf(new(GuildDeleteEvent)):                "GUILD_DELETE",
+		reflect.TypeOf(new(GuildBanAddEvent)):                "GUILD_BAN_ADD",
+		reflect.TypeOf(new(GuildBanRemoveEvent)):             "GUILD_BAN_REMOVE",
+		reflect.TypeOf(new(GuildEmojisUpdateEvent)):          "GUILD_EMOJIS_UPDATE",
+		reflect.TypeOf(new(GuildIntegrationsUpdateEvent)):    "GUILD_INTEGRATIONS_UPDATE",
+		reflect.TypeOf(new(GuildMemberAddEvent)):             "GUILD_MEMBER_ADD",
+		reflect.TypeOf(new(GuildMemberRemoveEvent)):          "GUILD_MEMBER_REMOVE",
+		reflect.TypeOf(new(GuildMemberUpdateEvent)):          "GUILD_MEMBER_UPDATE",
+		reflect.TypeOf(new(GuildMembersChunkEvent)):          "GUILD_MEMBERS_CHUNK",
+		reflect.TypeOf(new(GuildRoleCreateEvent)):            "GUILD_ROLE_CREATE",
+		reflect.TypeOf(new(GuildRoleUpdateEvent)):            "GUILD_ROLE_UPDATE",
+		reflect.TypeOf(new(GuildRoleDeleteEvent)):            "GUILD_ROLE_DELETE",
+		reflect.TypeOf(new(InviteCreateEvent)):               "INVITE_CREATE",
+		reflect.TypeOf(new(InviteDeleteEvent)):               "INVITE_DELETE",
+		reflect.TypeOf(new(MessageCreateEvent)):              "MESSAGE_CREATE",
+		reflect.TypeOf(new(MessageUpdateEvent)):              "MESSAGE_UPDATE",
+		reflect.TypeOf(new(MessageDeleteEvent)):              "MESSAGE_DELETE",
+		reflect.TypeOf(new(MessageDeleteBulkEvent)):          "MESSAGE_DELETE_BULK",
+		reflect.TypeOf(new(MessageReactionAddEvent)):         "MESSAGE_REACTION_ADD",
+		reflect.TypeOf(new(MessageReactionRemoveEvent)):      "MESSAGE_REACTION_REMOVE",
+		reflect.TypeOf(new(MessageReactionRemoveAllEvent)):   "MESSAGE_REACTION_REMOVE_ALL",
+		reflect.TypeOf(new(MessageReactionRemoveEmojiEvent)): "MESSAGE_REACTION_REMOVE_EMOJI",
+		reflect.TypeOf(new(MessageAckEvent)):                 "MESSAGE_ACK",
+		reflect.TypeOf(new(PresenceUpdateEvent)):             "PRESENCE_UPDATE",
+		reflect.TypeOf(new(PresencesReplaceEvent)):           "PRESENCES_REPLACE",
+		reflect.TypeOf(new(SessionsReplaceEvent)):            "SESSIONS_REPLACE",
+		reflect.TypeOf(new(TypingStartEvent)):                "TYPING_START",
+		reflect.TypeOf(new(UserUpdateEvent)):                 "USER_UPDATE",
+		reflect.TypeOf(new(ReadyEvent)):                      "READY",
+		reflect.TypeOf(new(RelationshipAddEvent)):            "RELATIONSHIP_ADD",
+		reflect.TypeOf(new(RelationshipRemoveEvent)):         "RELATIONSHIP_REMOVE",
+		reflect.TypeOf(new(UserGuildSettingsUpdateEvent)):    "USER_GUILD_SETTINGS_UPDATE",
+		reflect.TypeOf(new(UserSettingsUpdateEvent)):         "USER_SETTINGS_UPDATE",
+		reflect.TypeOf(new(UserNoteUpdateEvent)):             "USER_NOTE_UPDATE",
+		reflect.TypeOf(new(VoiceStateUpdateEvent)):           "VOICE_STATE_UPDATE",
+		reflect.TypeOf(new(VoiceServerUpdateEvent)):          "VOICE_SERVER_UPDATE",
+		reflect.TypeOf(new(WebhooksUpdateEvent)):             "WEBHOOKS_UPDATE",
+
+		// situation-specific and custom events
+		reflect.TypeOf(new(GuildReadyEvent)):        "GUILD_READY",
+		reflect.TypeOf(new(GuildAvailableEvent)):    "GUILD_AVAILABLE",
+		reflect.TypeOf(new(GuildJoinEvent)):         "GUILD_JOIN",
+		reflect.TypeOf(new(GuildUnavailableEvent)):  "GUILD_UNAVAILABLE",
+		reflect.TypeOf(new(GuildLeaveEvent)):        "GUILD_LEAVE",
+		reflect.TypeOf(new(CloseEvent)):             "CLOSE",
+		reflect.TypeOf(new(UserTypingEvent)):        "USER_TYPING",
+		reflect.TypeOf(new(UserStoppedTypingEvent)): "USER_STOPPED_TYPING",
+		reflect.TypeOf(new(GuildFullyCachedEvent)):  "GUILD_FULLY_CACHED",
+		reflect.TypeOf(new(ShardRecoveredEvent)):    "SHARD_RECOVERED",
+	}
+
+	// eventTypes is the reverse of eventNames.
+	eventTypes = make(map[string]reflect.Type, len(eventNames))
+)
+
+func init() {
+	for t, name := range eventNames {
+		eventTypes[name] = t
+	}
+}
+
+// EventName returns the name of the passed event, e.g. "MESSAGE_CREATE" for
+// a *MessageCreateEvent.
+// e must be a pointer to an event, and may be nil, e.g.
+// (*MessageCreateEvent)(nil).
+//
+// Events received from the gateway are named after their Discord event name.
+// Situation-specific and custom events, which have no Discord equivalent,
+// follow the same naming scheme, e.g. "GUILD_READY" for a *GuildReadyEvent.
+//
+// If e is not an event, ok will be false.
+func EventName(e interface{}) (name string, ok bool) {
+	name, ok = eventNames[reflect.TypeOf(e)]
+	return
+}
+
+// EventType returns the type of the event with the passed name, as returned
+// by EventName.
+// The returned type is a pointer type, e.g. *MessageCreateEvent for
+// "MESSAGE_CREATE".
+//
+// If there is no event with the passed name, ok will be false.
+func EventType(name string) (t reflect.Type, ok bool) {
+	t, ok = eventTypes[name]
+	return
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/pkg/errors"
+)
+
+// GuildExportVersion is the version of the format written by ExportGuild.
+// It will be incremented, if a breaking change is made to the format.
+const GuildExportVersion = 1
+
+// GuildExport is the JSON document written by ExportGuild.
+//
+// All data is taken from the Cabinet only, therefore, resources that aren't
+// cached will be missing.
+// Slices of resources that aren't cached at all, will be empty, and never
+// null.
+type GuildExport struct {
+	// Version is the version of the format, i.e. GuildExportVersion.
+	Version int `json:"version"`
+	// ExportedAt is the time the export was created.
+	ExportedAt time.Time `json:"exported_at"`
+
+	// Guild is the exported guild.
+	Guild discord.Guild `json:"guild"`
+	// Channels are the cached channels of the guild.
+	Channels []discord.Channel `json:"channels"`
+	// Roles are the cached roles of the guild.
+	Roles []discord.Role `json:"roles"`
+	// Emojis are the cached emojis of the guild.
+	Emojis []discord.Emoji `json:"emojis"`
+	// Members are the cached members of the guild.
+	Members []discord.Member `json:"members"`
+	// Presences are the cached presences of the guild's members.
+	Presences []gateway.Presence `json:"presences"`
+	// VoiceStates are the cached voice states of the guild.
+	VoiceStates []discord.VoiceState `json:"voice_states"`
+	// Messages are the messages cached in the guild's channels.
+	// The amount of messages per channel is limited by the MessageStore's
+	// MaxMessages.
+	Messages []discord.Message `json:"messages"`
+}
+
+// ExportGuild serializes all cached data of the guild with the passed id as a
+// GuildExport and writes the resulting JSON to w.
+//
+// ExportGuild only reads from the Cabinet and never makes API calls.
+// If the guild itself is not cached, an error will be returned.
+func (s *State) ExportGuild(ctx context.Context, guildID discord.GuildID, w io.Writer) error {
+	g, err := s.Cabinet.Guild(guildID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get guild from cabinet")
+	}
+
+	e := GuildExport{
+		Version:     GuildExportVersion,
+		ExportedAt:  time.Now().UTC(),
+		Guild:       *g,
+		Channels:    []discord.Channel{},
+		Roles:       []discord.Role{},
+		Emojis:      []discord.Emoji{},
+		Members:     []discord.Member{},
+		Presences:   []gateway.Presence{},
+		VoiceStates: []discord.VoiceState{},
+		Messages:    []discord.Message{},
+	}
+
+	if c, err := s.Cabinet.Channels(guildID); err == nil {
+		e.Channels = append(e.Channels, c...)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return errors.Wrap(err, "failed to get channels from cabinet")
+	}
+
+	if r, err := s.Cabinet.Roles(guildID); err == nil {
+		e.Roles = append(e.Roles, r...)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return errors.Wrap(err, "failed to get roles from cabinet")
+	}
+
+	if em, err := s.Cabinet.Emojis(guildID); err == nil {
+		e.Emojis = append(e.Emojis, em...)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return errors.Wrap(err, "failed to get emojis from cabinet")
+	}
+
+	if m, err := s.Cabinet.Members(guildID); err == nil {
+		e.Members = append(e.Members, m...)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return errors.Wrap(err, "failed to get members from cabinet")
+	}
+
+	if p, err := s.Cabinet.Presences(guildID); err == nil {
+		e.Presences = append(e.Presences, p...)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return errors.Wrap(err, "failed to get presences from cabinet")
+	}
+
+	if vs, err := s.Cabinet.VoiceStates(guildID); err == nil {
+		e.VoiceStates = append(e.VoiceStates, vs...)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return errors.Wrap(err, "failed to get voice states from cabinet")
+	}
+
+	for _, c := range e.Channels {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		msgs, err := s.Cabinet.Messages(c.ID)
+		if err == nil {
+			e.Messages = append(e.Messages, msgs...)
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return errors.Wrapf(err, "failed to get messages of channel %d from cabinet", c.ID)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return errors.Wrap(json.NewEncoder(w).Encode(e), "failed to encode guild export")
+}
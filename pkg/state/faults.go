@@ -0,0 +1,124 @@
+package state
+
+import (
+	"math/rand"
+	"time"
+)
+
+// FaultInjection configures the faults injected into the events received from
+// the gateway.
+//
+// It is intended for testing only, to validate that handlers cope with the
+// behavior of the real gateway, such as delayed, lost and duplicated events,
+// and reconnects.
+type FaultInjection struct {
+	// DelayProbability is the probability that an event is delayed.
+	DelayProbability float64
+	// MaxDelay is the maximum delay of a delayed event.
+	// The actual delay is chosen randomly between 0 and MaxDelay.
+	// Delaying an event also delays all events received after it.
+	MaxDelay time.Duration
+
+	// DropProbability is the probability that an event is dropped.
+	DropProbability float64
+	// DuplicateProbability is the probability that an event is dispatched
+	// twice.
+	DuplicateProbability float64
+
+	// ReconnectInterval is the interval in which the gateway is forcibly
+	// reconnected.
+	// If ReconnectInterval is 0, the gateway is never reconnected.
+	ReconnectInterval time.Duration
+
+	// Seed is the seed used to decide which faults are injected.
+	// Using the same seed, the same sequence of faults is injected.
+	Seed int64
+}
+
+// injectFaults starts injecting faults into the passed events channel, using
+// the EventHandler's FaultInjection, until closer is closed.
+// It returns the channel to read the events from.
+//
+// If stop is closed, or the events channel is closed, injectFaults stops
+// reading events, and closes the returned channel after forwarding the event
+// it is currently delaying.
+func (h *EventHandler) injectFaults(
+	events <-chan interface{}, stop, closer <-chan struct{},
+) <-chan interface{} {
+	f := *h.FaultInjection
+	r := rand.New(rand.NewSource(f.Seed))
+
+	out := make(chan interface{})
+
+	send := func(e interface{}) bool {
+		select {
+		case <-closer:
+			return false
+		case out <- e:
+			return true
+		}
+	}
+
+	go func() {
+		for {
+			var (
+				e  interface{}
+				ok bool
+			)
+
+			select {
+			case <-closer:
+				return
+			case <-stop:
+				close(out)
+				return
+			case e, ok = <-events:
+				if !ok {
+					close(out)
+					return
+				}
+			}
+
+			if r.Float64() < f.DropProbability {
+				continue
+			}
+
+			if f.MaxDelay > 0 && r.Float64() < f.DelayProbability {
+				t := time.NewTimer(time.Duration(r.Int63n(int64(f.MaxDelay))))
+
+				select {
+				case <-closer:
+					t.Stop()
+					return
+				case <-t.C:
+				}
+			}
+
+			if !send(e) {
+				return
+			}
+
+			if r.Float64() < f.DuplicateProbability && !send(e) {
+				return
+			}
+		}
+	}()
+
+	if f.ReconnectInterval > 0 {
+		go func() {
+			t := time.NewTicker(f.ReconnectInterval)
+			defer t.Stop()
+
+			for {
+				select {
+				case <-closer:
+					return
+				case <-t.C:
+					h.s.Gateway.Reconnect()
+				}
+			}
+		}()
+	}
+
+	return out
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventHandler_injectFaults(t *testing.T) {
+	// inject sends the events 0 to n-1 through injectFaults using the passed
+	// FaultInjection, and returns the events that were forwarded.
+	inject := func(t *testing.T, f FaultInjection, n int) (forwarded []interface{}) {
+		_, s := NewMocker(t)
+		s.FaultInjection = &f
+
+		events := make(chan interface{})
+		stop := make(chan struct{})
+		closer := make(chan struct{})
+
+		defer close(closer)
+
+		out := s.injectFaults(events, stop, closer)
+
+		done := make(chan struct{})
+
+		go func() {
+			for e := range out {
+				forwarded = append(forwarded, e)
+			}
+
+			close(done)
+		}()
+
+		for i := 0; i < n; i++ {
+			events <- i
+		}
+
+		close(stop)
+		<-done
+
+		return forwarded
+	}
+
+	t.Run("no faults", func(t *testing.T) {
+		forwarded := inject(t, FaultInjection{}, 3)
+		assert.Equal(t, []interface{}{0, 1, 2}, forwarded)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		forwarded := inject(t, FaultInjection{DropProbability: 1}, 3)
+		assert.Empty(t, forwarded)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		forwarded := inject(t, FaultInjection{DuplicateProbability: 1}, 2)
+		assert.Equal(t, []interface{}{0, 0, 1, 1}, forwarded)
+	})
+
+	t.Run("seed", func(t *testing.T) {
+		f := FaultInjection{DropProbability: 0.5, DuplicateProbability: 0.5, Seed: 42}
+
+		a := inject(t, f, 20)
+		b := inject(t, f, 20)
+
+		assert.Equal(t, a, b)
+	})
+}
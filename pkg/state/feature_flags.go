@@ -0,0 +1,136 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+// FeatureFlagProvider provides the state of feature flags.
+type FeatureFlagProvider interface {
+	// FeatureEnabled checks if the feature with the passed name is enabled
+	// in the guild with the passed id.
+	// guildID is 0 for events that don't belong to a guild.
+	FeatureEnabled(feature string, guildID discord.GuildID) (bool, error)
+}
+
+type (
+	// featureFlags caches the feature flags returned by a
+	// FeatureFlagProvider.
+	featureFlags struct {
+		provider FeatureFlagProvider
+		ttl      time.Duration
+
+		cache map[featureKey]cachedFeature
+		mutex sync.Mutex
+	}
+
+	featureKey struct {
+		feature string
+		guildID discord.GuildID
+	}
+
+	cachedFeature struct {
+		enabled bool
+		expires time.Time
+	}
+)
+
+// SetFeatureFlagProvider sets the FeatureFlagProvider used by the
+// FeatureEnabled middleware.
+// The flags returned by the provider are cached per guild for the passed
+// ttl.
+// If ttl is 0, flags are cached until they are invalidated using
+// InvalidateFeature or InvalidateFeatures.
+//
+// SetFeatureFlagProvider must be called before the State is opened.
+func (s *State) SetFeatureFlagProvider(p FeatureFlagProvider, ttl time.Duration) {
+	s.features = &featureFlags{
+		provider: p,
+		ttl:      ttl,
+		cache:    make(map[featureKey]cachedFeature),
+	}
+}
+
+// InvalidateFeature removes the cached state of the passed feature in the
+// guild with the passed id, so that the FeatureFlagProvider is consulted
+// again the next time the feature is checked.
+func (s *State) InvalidateFeature(feature string, guildID discord.GuildID) {
+	if s.features == nil {
+		return
+	}
+
+	s.features.mutex.Lock()
+	delete(s.features.cache, featureKey{feature: feature, guildID: guildID})
+	s.features.mutex.Unlock()
+}
+
+// InvalidateFeatures removes the cached states of all features.
+func (s *State) InvalidateFeatures() {
+	if s.features == nil {
+		return
+	}
+
+	s.features.mutex.Lock()
+	s.features.cache = make(map[featureKey]cachedFeature)
+	s.features.mutex.Unlock()
+}
+
+// FeatureEnabled checks if the passed feature is enabled in the guild with the
+// passed id, using the FeatureFlagProvider set through
+// SetFeatureFlagProvider.
+// If no provider was set, all features are enabled.
+func (s *State) FeatureEnabled(feature string, guildID discord.GuildID) (bool, error) {
+	f := s.features
+	if f == nil {
+		return true, nil
+	}
+
+	key := featureKey{feature: feature, guildID: guildID}
+
+	f.mutex.Lock()
+	c, ok := f.cache[key]
+	f.mutex.Unlock()
+
+	if ok && (c.expires.IsZero() || time.Now().Before(c.expires)) {
+		return c.enabled, nil
+	}
+
+	enabled, err := f.provider.FeatureEnabled(feature, guildID)
+	if err != nil {
+		return false, err
+	}
+
+	c = cachedFeature{enabled: enabled}
+	if f.ttl > 0 {
+		c.expires = time.Now().Add(f.ttl)
+	}
+
+	f.mutex.Lock()
+	f.cache[key] = c
+	f.mutex.Unlock()
+
+	return enabled, nil
+}
+
+// FeatureEnabled returns a middleware that filters all events, for whose
+// guild the passed feature is disabled.
+// Filtered events are reported to OnFiltered with a *FilterError.
+//
+// If the FeatureFlagProvider returns an error, the error is returned by the
+// middleware.
+func FeatureEnabled(feature string) func(*State, interface{}) error {
+	return func(s *State, e interface{}) error {
+		enabled, err := s.FeatureEnabled(feature, eventGuildID(e))
+		if err != nil {
+			return err
+		}
+
+		if !enabled {
+			return &FilterError{Reason: "feature " + feature + " is disabled"}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testFeatureFlags struct {
+	enabled map[discord.GuildID]bool
+	err     error
+
+	calls int
+	mutex sync.Mutex
+}
+
+func (p *testFeatureFlags) FeatureEnabled(_ string, guildID discord.GuildID) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.calls++
+	return p.enabled[guildID], p.err
+}
+
+func TestState_FeatureEnabled(t *testing.T) {
+	t.Run("no provider", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		enabled, err := s.FeatureEnabled("abc", 1)
+		require.NoError(t, err)
+		assert.True(t, enabled)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		p := &testFeatureFlags{enabled: map[discord.GuildID]bool{1: true}}
+		s.SetFeatureFlagProvider(p, 0)
+
+		for i := 0; i < 2; i++ {
+			enabled, err := s.FeatureEnabled("abc", 1)
+			require.NoError(t, err)
+			assert.True(t, enabled)
+		}
+
+		assert.Equal(t, 1, p.calls)
+
+		s.InvalidateFeature("abc", 1)
+
+		p.enabled[1] = false
+
+		enabled, err := s.FeatureEnabled("abc", 1)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+		assert.Equal(t, 2, p.calls)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		p := &testFeatureFlags{err: errors.New("abc")}
+		s.SetFeatureFlagProvider(p, 0)
+
+		_, err := s.FeatureEnabled("abc", 1)
+		assert.Equal(t, p.err, err)
+
+		// errors aren't cached
+		_, err = s.FeatureEnabled("abc", 1)
+		assert.Equal(t, p.err, err)
+		assert.Equal(t, 2, p.calls)
+	})
+}
+
+func TestFeatureEnabled(t *testing.T) {
+	_, s := NewMocker(t)
+	s.SetFeatureFlagProvider(&testFeatureFlags{enabled: map[discord.GuildID]bool{1: true}}, 0)
+
+	reasons := make(chan string, 1)
+	s.OnFiltered = func(_ interface{}, _ string, reason string) { reasons <- reason }
+
+	received := make(chan discord.GuildID, 2)
+
+	s.MustAddHandler(func(_ *State, e *MessageCreateEvent) {
+		received <- e.GuildID
+	}, FeatureEnabled("abc"))
+
+	for _, guildID := range []discord.GuildID{1, 2} {
+		e := newMessageCreateEvent()
+		e.GuildID = guildID
+
+		s.Call(e)
+	}
+
+	s.wg.Wait()
+
+	require.Len(t, received, 1)
+	assert.Equal(t, discord.GuildID(1), <-received)
+
+	require.Len(t, reasons, 1)
+	assert.Equal(t, "feature abc is disabled", <-reasons)
+}
@@ -0,0 +1,20 @@
+package state
+
+// FilterError is an error that can be returned by a middleware or handler, to
+// signal that an event was filtered, stating the reason why.
+//
+// Like Filtered, it is not passed to the ErrorHandler, and errors.Is reports
+// FilterErrors as Filtered.
+type FilterError struct {
+	// Reason is the reason the event was filtered.
+	Reason string
+}
+
+func (e *FilterError) Error() string {
+	return "filtered: " + e.Reason
+}
+
+// Is reports whether target is Filtered.
+func (e *FilterError) Is(target error) bool {
+	return target == Filtered
+}
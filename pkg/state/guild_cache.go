@@ -0,0 +1,362 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/pkg/errors"
+)
+
+// DeferLargeGuildCaching defers the caching of the members and presences of
+// guilds with at least the passed number of members.
+//
+// The guild itself, its channels, roles, emojis and voice states are stored
+// immediately when the GuildCreateEvent is received, while members and
+// presences are stored by a background worker, so that large guilds don't
+// delay the dispatching of subsequent events.
+// Once all members and presences of a guild were stored, a
+// GuildFullyCachedEvent is dispatched.
+//
+// Handlers of the GuildCreateEvent still receive the full member and presence
+// lists.
+// However, until the GuildFullyCachedEvent is dispatched, members and
+// presences of the guild may be missing from the Cabinet, and the Old fields
+// of events concerning them may therefore be nil.
+// Members and presences that were updated or removed by a later event, before
+// the worker stored them, are skipped, so that the worker never overwrites
+// newer data.
+//
+// Errors that occur while storing are passed to the EventHandler's
+// StateErrorHandler, along with the GuildCreateEvent.
+//
+// DeferLargeGuildCaching must be called before the State is opened.
+func (s *State) DeferLargeGuildCaching(threshold int) {
+	s.guildCache = &guildCacheWorker{
+		s:         s,
+		threshold: threshold,
+		pending:   make(map[discord.GuildID]*guildCacheJob),
+	}
+}
+
+// guildCacheBatchSize is the maximum number of members or presences the
+// guildCacheWorker stores at once, while blocking state updates.
+const guildCacheBatchSize = 1000
+
+type (
+	// guildCacheWorker stores the members and presences of large guilds in
+	// the background.
+	guildCacheWorker struct {
+		s         *State
+		threshold int
+
+		jobs []*guildCacheJob
+		// pending are the jobs that are queued or processed, by the id of
+		// their guild.
+		pending map[discord.GuildID]*guildCacheJob
+		running bool
+		// mutex guards the fields of the worker and its jobs.
+		// Additionally, it is held while state updates of events concerning
+		// members or presences of a pending guild are applied, and while the
+		// worker stores a batch, so that the two never interleave.
+		mutex sync.Mutex
+	}
+
+	guildCacheJob struct {
+		event     *gateway.GuildCreateEvent
+		members   []discord.Member
+		presences []gateway.Presence
+
+		// updatedMembers and updatedPresences are the ids of the users whose
+		// member or presence was updated or removed by an event received
+		// after the GuildCreateEvent.
+		updatedMembers   map[discord.UserID]struct{}
+		updatedPresences map[discord.UserID]struct{}
+		// dropped specifies whether the job was invalidated, because the
+		// guild was deleted, or a newer GuildCreateEvent was received.
+		dropped bool
+	}
+)
+
+// strip checks if the passed gateway event is a GuildCreateEvent of a large
+// guild.
+// If so, it returns a copy of the event without members and presences, that
+// should be used to update the state, and the job to store the members and
+// presences.
+// Otherwise, it returns the event unchanged.
+func (w *guildCacheWorker) strip(e interface{}) (interface{}, *guildCacheJob) {
+	gc, ok := e.(*gateway.GuildCreateEvent)
+	if !ok || gc.Unavailable || int(gc.MemberCount) < w.threshold {
+		return e, nil
+	}
+
+	cp := *gc
+	cp.Members = nil
+	cp.Presences = nil
+
+	return &cp, &guildCacheJob{
+		event:            gc,
+		members:          gc.Members,
+		presences:        gc.Presences,
+		updatedMembers:   make(map[discord.UserID]struct{}),
+		updatedPresences: make(map[discord.UserID]struct{}),
+	}
+}
+
+// update updates the state using the stripped version of the passed gateway
+// event.
+// If the event concerns members or presences of a guild with a pending job,
+// the affected users are marked, so that the worker won't overwrite them
+// with the outdated data of the job.
+func (w *guildCacheWorker) update(gatewayEvent, stripped interface{}) {
+	var (
+		guildID   discord.GuildID
+		members   []discord.UserID
+		presences []discord.UserID
+	)
+
+	switch e := gatewayEvent.(type) {
+	case *gateway.GuildMemberAddEvent:
+		guildID, members = e.GuildID, []discord.UserID{e.User.ID}
+	case *gateway.GuildMemberUpdateEvent:
+		guildID, members = e.GuildID, []discord.UserID{e.User.ID}
+	case *gateway.GuildMemberRemoveEvent:
+		guildID = e.GuildID
+		members = []discord.UserID{e.User.ID}
+		presences = members
+	case *gateway.PresenceUpdateEvent:
+		guildID, presences = e.GuildID, []discord.UserID{e.User.ID}
+	case *gateway.GuildMembersChunkEvent:
+		guildID = e.GuildID
+
+		members = make([]discord.UserID, len(e.Members))
+		for i, m := range e.Members {
+			members[i] = m.User.ID
+		}
+
+		presences = make([]discord.UserID, len(e.Presences))
+		for i, p := range e.Presences {
+			presences[i] = p.User.ID
+		}
+	case *gateway.GuildDeleteEvent:
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+
+		if job := w.pending[e.ID]; job != nil {
+			job.dropped = true
+			delete(w.pending, e.ID)
+		}
+
+		w.s.Session.Call(stripped)
+		return
+	default:
+		w.s.Session.Call(stripped)
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if job := w.pending[guildID]; job != nil {
+		for _, id := range members {
+			job.updatedMembers[id] = struct{}{}
+		}
+
+		for _, id := range presences {
+			job.updatedPresences[id] = struct{}{}
+		}
+	}
+
+	w.s.Session.Call(stripped)
+}
+
+// enqueue queues the passed job, and starts the worker, if it isn't already
+// running.
+// A pending job of the same guild is dropped, as it is outdated.
+func (w *guildCacheWorker) enqueue(job *guildCacheJob) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if old := w.pending[job.event.ID]; old != nil {
+		old.dropped = true
+	}
+
+	w.pending[job.event.ID] = job
+	w.jobs = append(w.jobs, job)
+
+	if !w.running {
+		w.running = true
+
+		w.s.wg.Add(1)
+		go w.work()
+	}
+}
+
+// reset discards all queued jobs, and stops processing the current one.
+func (w *guildCacheWorker) reset() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, job := range w.pending {
+		job.dropped = true
+	}
+
+	w.jobs = nil
+	w.pending = make(map[discord.GuildID]*guildCacheJob)
+}
+
+// work processes queued jobs until the queue is empty.
+func (w *guildCacheWorker) work() {
+	defer w.s.wg.Done()
+
+	for {
+		w.mutex.Lock()
+
+		if len(w.jobs) == 0 {
+			w.running = false
+			w.mutex.Unlock()
+			return
+		}
+
+		job := w.jobs[0]
+		w.jobs = w.jobs[1:]
+
+		w.mutex.Unlock()
+
+		w.process(job)
+	}
+}
+
+func (w *guildCacheWorker) process(job *guildCacheJob) {
+	guildID := job.event.ID
+
+	// the guild may have been left in the meantime
+	if _, err := w.s.Cabinet.Guild(guildID); err != nil {
+		w.finish(job)
+		return
+	}
+
+	for len(job.members) > 0 {
+		n := guildCacheBatchSize
+		if n > len(job.members) {
+			n = len(job.members)
+		}
+
+		if !w.storeMembers(job, job.members[:n]) {
+			return
+		}
+
+		job.members = job.members[n:]
+	}
+
+	for len(job.presences) > 0 {
+		n := guildCacheBatchSize
+		if n > len(job.presences) {
+			n = len(job.presences)
+		}
+
+		if !w.storePresences(job, job.presences[:n]) {
+			return
+		}
+
+		job.presences = job.presences[n:]
+	}
+
+	if !w.finish(job) {
+		return
+	}
+
+	w.s.chunkedGuilds.Add(guildID)
+
+	w.s.dispatch(&GuildFullyCachedEvent{
+		Base:    NewBase(),
+		GuildID: guildID,
+	})
+}
+
+// storeMembers stores the passed members of the job's guild, that weren't
+// updated since the job was created.
+// It returns false, if the job was dropped.
+func (w *guildCacheWorker) storeMembers(job *guildCacheJob, members []discord.Member) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if job.dropped {
+		return false
+	}
+
+	filtered := make([]discord.Member, 0, len(members))
+
+	for _, m := range members {
+		if _, ok := job.updatedMembers[m.User.ID]; !ok {
+			filtered = append(filtered, m)
+		}
+	}
+
+	if ms, ok := w.s.Cabinet.MemberStore.(BatchMemberStore); ok {
+		if err := ms.MembersSet(job.event.ID, filtered); err != nil {
+			w.s.stateError(errors.Wrap(err, "failed to batch set deferred guild members"), job.event)
+		}
+
+		return true
+	}
+
+	for _, m := range filtered {
+		if err := w.s.Cabinet.MemberSet(job.event.ID, m); err != nil {
+			w.s.stateError(errors.Wrap(err, "failed to set deferred guild member"), job.event)
+		}
+	}
+
+	return true
+}
+
+// storePresences stores the passed presences of the job's guild, that weren't
+// updated since the job was created.
+// It returns false, if the job was dropped.
+func (w *guildCacheWorker) storePresences(job *guildCacheJob, presences []gateway.Presence) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if job.dropped {
+		return false
+	}
+
+	filtered := make([]gateway.Presence, 0, len(presences))
+
+	for _, p := range presences {
+		if _, ok := job.updatedPresences[p.User.ID]; !ok {
+			filtered = append(filtered, p)
+		}
+	}
+
+	if ps, ok := w.s.Cabinet.PresenceStore.(BatchPresenceStore); ok {
+		if err := ps.PresencesSet(job.event.ID, filtered); err != nil {
+			w.s.stateError(errors.Wrap(err, "failed to batch set deferred guild presences"), job.event)
+		}
+
+		return true
+	}
+
+	for _, p := range filtered {
+		if err := w.s.Cabinet.PresenceSet(job.event.ID, p); err != nil {
+			w.s.stateError(errors.Wrap(err, "failed to set deferred guild presence"), job.event)
+		}
+	}
+
+	return true
+}
+
+// finish removes the passed job from the pending jobs.
+// It returns false, if the job was dropped.
+func (w *guildCacheWorker) finish(job *guildCacheJob) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if job.dropped {
+		return false
+	}
+
+	delete(w.pending, job.event.ID)
+
+	return true
+}
@@ -80,9 +80,16 @@ type GuildBanAddEvent struct {
 }
 
 // https://discord.com/developers/docs/topics/gateway#guild-ban-remove
+//
+// Old is taken from the ban cache, which must be populated using
+// State.WarmBans.
+// The Reason of bans added after the ban cache was populated may be empty,
+// see State.IsBanned for details.
 type GuildBanRemoveEvent struct {
 	*gateway.GuildBanRemoveEvent
 	*Base
+
+	Old *discord.Ban
 }
 
 // https://discord.com/developers/docs/topics/gateway#guild-emojis-update
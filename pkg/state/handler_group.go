@@ -0,0 +1,165 @@
+package state
+
+import "sync"
+
+// HandlerGroup is a group of handlers that share middlewares and can be
+// removed together.
+//
+// Handlers are added to the EventHandler the group was created from, using
+// the middlewares of the group's parent groups, followed by the group's own
+// middlewares and the middlewares passed when adding the handler.
+// Like all per-handler middlewares, these must either be interface{} or Base
+// middlewares, or have the same type as the handlers they are used with.
+type HandlerGroup struct {
+	h      *EventHandler
+	parent *HandlerGroup
+
+	middlewares MiddlewareChain
+	rms         []func()
+	children    []*HandlerGroup
+	mutex       sync.Mutex
+}
+
+// Group creates a new HandlerGroup using the passed middlewares.
+func (h *EventHandler) Group(middlewares ...interface{}) *HandlerGroup {
+	return &HandlerGroup{
+		h:           h,
+		middlewares: NewMiddlewareChain(middlewares...),
+	}
+}
+
+// Group creates a new sub-group of the HandlerGroup, that inherits the
+// middlewares of the group.
+// Handlers of the sub-group will be removed, when RemoveAll is called on the
+// parent group.
+func (g *HandlerGroup) Group(middlewares ...interface{}) *HandlerGroup {
+	child := &HandlerGroup{
+		h:           g.h,
+		parent:      g,
+		middlewares: NewMiddlewareChain(middlewares...),
+	}
+
+	g.mutex.Lock()
+	g.children = append(g.children, child)
+	g.mutex.Unlock()
+
+	return child
+}
+
+// AddMiddleware adds the passed middlewares to the group.
+// They will only be used for handlers that are added after the call to
+// AddMiddleware.
+func (g *HandlerGroup) AddMiddleware(middlewares ...interface{}) {
+	g.mutex.Lock()
+	g.middlewares = g.middlewares.With(middlewares...)
+	g.mutex.Unlock()
+}
+
+// AddHandler adds the passed handler to the EventHandler, using the
+// middlewares of the group followed by the passed middlewares.
+// Refer to EventHandler.AddHandler for more information.
+func (g *HandlerGroup) AddHandler(handler interface{}, middlewares ...interface{}) (rm func(), err error) {
+	return g.addHandler(handler, false, HandlerOptions{}, middlewares)
+}
+
+// MustAddHandler is the same as AddHandler, but panics if AddHandler returns
+// an error.
+func (g *HandlerGroup) MustAddHandler(handler interface{}, middlewares ...interface{}) func() {
+	rm, err := g.AddHandler(handler, middlewares...)
+	if err != nil {
+		panic(err)
+	}
+
+	return rm
+}
+
+// AddHandlerOnce adds a handler that is only executed once, using the
+// middlewares of the group followed by the passed middlewares.
+// Refer to EventHandler.AddHandlerOnce for more information.
+func (g *HandlerGroup) AddHandlerOnce(handler interface{}, middlewares ...interface{}) error {
+	_, err := g.addHandler(handler, true, HandlerOptions{}, middlewares)
+	return err
+}
+
+// MustAddHandlerOnce is the same as AddHandlerOnce, but panics if
+// AddHandlerOnce returns an error.
+func (g *HandlerGroup) MustAddHandlerOnce(handler interface{}, middlewares ...interface{}) {
+	err := g.AddHandlerOnce(handler, middlewares...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// AddHandlerWithOptions is the same as AddHandler, but additionally allows
+// specifying HandlerOptions.
+func (g *HandlerGroup) AddHandlerWithOptions(
+	handler interface{}, o HandlerOptions, middlewares ...interface{},
+) (rm func(), err error) {
+	return g.addHandler(handler, false, o, middlewares)
+}
+
+// MustAddHandlerWithOptions is the same as AddHandlerWithOptions, but panics
+// if AddHandlerWithOptions returns an error.
+func (g *HandlerGroup) MustAddHandlerWithOptions(
+	handler interface{}, o HandlerOptions, middlewares ...interface{},
+) func() {
+	rm, err := g.AddHandlerWithOptions(handler, o, middlewares...)
+	if err != nil {
+		panic(err)
+	}
+
+	return rm
+}
+
+func (g *HandlerGroup) addHandler(
+	handler interface{}, execOnce bool, o HandlerOptions, middlewares []interface{},
+) (rm func(), err error) {
+	middlewares = append([]interface{}{g.allMiddlewares()}, middlewares...)
+
+	rm, err = g.h.addHandler(handler, execOnce, o, middlewares...)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mutex.Lock()
+	g.rms = append(g.rms, rm)
+	g.mutex.Unlock()
+
+	return rm, nil
+}
+
+// allMiddlewares returns the middlewares of the group's parents followed by
+// the group's own middlewares.
+func (g *HandlerGroup) allMiddlewares() MiddlewareChain {
+	g.mutex.Lock()
+	own := g.middlewares
+	g.mutex.Unlock()
+
+	if g.parent == nil {
+		return own
+	}
+
+	return NewMiddlewareChain(g.parent.allMiddlewares(), own)
+}
+
+// RemoveAll removes all handlers that were added through the group or one of
+// its sub-groups.
+// The group may still be used to add handlers afterwards.
+func (g *HandlerGroup) RemoveAll() {
+	g.mutex.Lock()
+
+	rms := g.rms
+	g.rms = nil
+
+	children := g.children
+
+	g.mutex.Unlock()
+
+	for _, rm := range rms {
+		rm()
+	}
+
+	for _, c := range children {
+		c.RemoveAll()
+	}
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerGroup(t *testing.T) {
+	t.Run("middlewares", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		var (
+			order []string
+			mutex sync.Mutex
+		)
+
+		record := func(name string) func(*State, interface{}) {
+			return func(*State, interface{}) {
+				mutex.Lock()
+				order = append(order, name)
+				mutex.Unlock()
+			}
+		}
+
+		parent := s.Group(record("parent"))
+		child := parent.Group(record("child"))
+
+		// only used for handlers added afterwards
+		parent.AddMiddleware(record("late"))
+
+		child.MustAddHandler(func(*State, *MessageCreateEvent) {
+			record("handler")(nil, nil)
+		}, record("handler middleware"))
+
+		s.Call(newMessageCreateEvent())
+		s.wg.Wait()
+
+		assert.Equal(t, []string{"parent", "late", "child", "handler middleware", "handler"}, order)
+	})
+
+	t.Run("remove all", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		var calls int32
+
+		handler := func(*State, *MessageCreateEvent) { atomic.AddInt32(&calls, 1) }
+
+		parent := s.Group()
+		parent.MustAddHandler(handler)
+		parent.Group().MustAddHandler(handler)
+
+		s.MustAddHandler(handler)
+
+		parent.RemoveAll()
+
+		s.Call(newMessageCreateEvent())
+		s.wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		// the group can still be used afterwards
+		parent.MustAddHandler(handler)
+
+		s.Call(newMessageCreateEvent())
+		s.wg.Wait()
+
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+}
@@ -0,0 +1,116 @@
+package state
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// HealthOptions are the options used to determine the health of a State.
+	HealthOptions struct {
+		// MaxHeartbeatAge is the maximum time since the last heartbeat
+		// acknowledgement, for the gateway to be considered connected.
+		//
+		// Defaults to twice the heartbeat interval sent by Discord.
+		MaxHeartbeatAge time.Duration
+		// MaxQueueLength is the maximum number of events waiting in the
+		// event queue, for the State to be considered ready.
+		// It only has an effect if the EventHandler's QueueSize is set.
+		//
+		// If MaxQueueLength is 0, the queue length is not checked.
+		MaxQueueLength int
+	}
+
+	// HealthStatus is the health of a State.
+	HealthStatus struct {
+		// Live specifies whether the gateway is connected, i.e. whether a
+		// heartbeat was acknowledged within the MaxHeartbeatAge.
+		Live bool `json:"live"`
+		// Ready specifies whether the State is Live, received a Ready event,
+		// all guilds sent during Ready became available, and the queue
+		// length is under MaxQueueLength.
+		Ready bool `json:"ready"`
+
+		// LastHeartbeatAck is the time the last heartbeat was acknowledged.
+		LastHeartbeatAck time.Time `json:"last_heartbeat_ack"`
+		// ReceivedReady specifies whether a Ready event was received.
+		ReceivedReady bool `json:"received_ready"`
+		// PendingGuilds is the number of guilds sent during Ready that
+		// haven't become available yet.
+		PendingGuilds int `json:"pending_guilds"`
+		// QueueLength is the number of events waiting in the event queue.
+		QueueLength int `json:"queue_length"`
+		// DroppedEvents is the number of events dropped by the event queue.
+		DroppedEvents uint64 `json:"dropped_events"`
+
+		// Stats are the dispatch statistics, if the EventHandler's Metrics
+		// are a *Stats.
+		Stats *StatsSnapshot `json:"stats,omitempty"`
+	}
+)
+
+// Health returns the HealthStatus of the State.
+func (s *State) Health(o HealthOptions) HealthStatus {
+	maxAge := o.MaxHeartbeatAge
+	if maxAge <= 0 {
+		maxAge = 2 * s.Gateway.PacerLoop.Heartrate.Get()
+	}
+
+	status := HealthStatus{
+		ReceivedReady: atomic.LoadUint32(&s.ready) == 1,
+		PendingGuilds: s.unreadyGuilds.Len(),
+		QueueLength:   s.queueLength(),
+		DroppedEvents: s.Dropped(),
+	}
+
+	if ack := s.Gateway.PacerLoop.EchoBeat.Get(); ack != 0 {
+		status.LastHeartbeatAck = time.Unix(0, ack)
+		status.Live = time.Since(status.LastHeartbeatAck) <= maxAge
+	}
+
+	status.Ready = status.Live && status.ReceivedReady && status.PendingGuilds == 0 &&
+		(o.MaxQueueLength <= 0 || status.QueueLength <= o.MaxQueueLength)
+
+	if stats, ok := s.Metrics.(*Stats); ok {
+		snap := stats.Snapshot()
+		status.Stats = &snap
+	}
+
+	return status
+}
+
+// HealthHandler returns an http.Handler that serves the health of the State.
+//
+// Requests to paths ending in "/livez" and "/readyz" are answered with status
+// 200, if the State is Live or Ready respectively, and status 503 otherwise.
+// All other requests are answered with the HealthStatus encoded as JSON.
+func (s *State) HealthHandler(o HealthOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := s.Health(o)
+
+		var ok bool
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/livez"):
+			ok = status.Live
+		case strings.HasSuffix(r.URL.Path, "/readyz"):
+			ok = status.Ready
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(status)
+
+			return
+		}
+
+		if ok {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("unavailable"))
+		}
+	})
+}
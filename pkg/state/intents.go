@@ -15,6 +15,7 @@ var eventIntents = map[reflect.Type]gateway.Intents{
 	reflect.TypeOf(new(GuildDeleteEvent)):       gateway.IntentGuilds,
 	reflect.TypeOf(new(GuildUnavailableEvent)):  gateway.IntentGuilds,
 	reflect.TypeOf(new(GuildLeaveEvent)):        gateway.IntentGuilds,
+	reflect.TypeOf(new(GuildFullyCachedEvent)):  gateway.IntentGuilds,
 	reflect.TypeOf(new(GuildRoleCreateEvent)):   gateway.IntentGuilds,
 	reflect.TypeOf(new(GuildRoleUpdateEvent)):   gateway.IntentGuilds,
 	reflect.TypeOf(new(GuildRoleDeleteEvent)):   gateway.IntentGuilds,
@@ -58,4 +59,7 @@ var eventIntents = map[reflect.Type]gateway.Intents{
 		gateway.IntentDirectMessageReactions,
 
 	reflect.TypeOf(new(TypingStartEvent)): gateway.IntentGuildMessageTyping | gateway.IntentDirectMessageTyping,
+	reflect.TypeOf(new(UserTypingEvent)):  gateway.IntentGuildMessageTyping | gateway.IntentDirectMessageTyping,
+	reflect.TypeOf(new(UserStoppedTypingEvent)): gateway.IntentGuildMessageTyping |
+		gateway.IntentDirectMessageTyping,
 }
@@ -0,0 +1,134 @@
+package state
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// nonceCounter is used to generate unique nonces for member requests.
+var nonceCounter uint64
+
+// MembersOf requests all members of the guild with the passed id from the
+// gateway, and returns them once all chunks were received.
+// The members are also stored in the Cabinet.
+//
+// MembersOf blocks until all chunks were received or the passed context is
+// canceled.
+// Requesting members requires the GUILD_MEMBERS intent, and the State must be
+// opened.
+func (s *State) MembersOf(ctx context.Context, guildID discord.GuildID) ([]discord.Member, error) {
+	members, _, err := s.requestMembers(ctx, gateway.RequestGuildMembersData{
+		GuildID: []discord.GuildID{guildID},
+	})
+
+	return members, err
+}
+
+// RequestGuildMembers requests the members specified by the passed
+// gateway.RequestGuildMembersData from the gateway, and returns them along
+// with their presences, once all chunks were received.
+// The members are also stored in the Cabinet.
+//
+// If data.Nonce is empty, a unique nonce will be generated, which is used to
+// tell the chunks of this request apart from those of other requests.
+//
+// RequestGuildMembers blocks until all chunks for all requested guilds were
+// received or the passed context is canceled.
+func (s *State) RequestGuildMembers(
+	ctx context.Context, data gateway.RequestGuildMembersData,
+) ([]discord.Member, []gateway.Presence, error) {
+	return s.requestMembers(ctx, data)
+}
+
+// requestMembers requests the members specified by the passed
+// gateway.RequestGuildMembersData from the gateway, and waits until all
+// chunks were received.
+//
+// If data.Nonce is empty, a unique nonce will be generated.
+func (s *State) requestMembers(
+	ctx context.Context, data gateway.RequestGuildMembersData,
+) ([]discord.Member, []gateway.Presence, error) {
+	if data.Nonce == "" {
+		data.Nonce = "disstate-" + strconv.FormatUint(atomic.AddUint64(&nonceCounter, 1), 36)
+	}
+
+	var (
+		mutex     sync.Mutex
+		members   []discord.Member
+		presences []gateway.Presence
+
+		// the number of chunks received per guild, and the number of guilds
+		// that are still missing chunks
+		received = make(map[discord.GuildID]int, len(data.GuildID))
+		pending  = len(data.GuildID)
+
+		// done is closed once all chunks were received
+		done = make(chan struct{})
+	)
+
+	// Use an internal handler, so that middlewares can't filter the chunks.
+	rm := s.addInternalHandler(func(gatewayEvent interface{}) {
+		c, ok := gatewayEvent.(*gateway.GuildMembersChunkEvent)
+		if !ok || c.Nonce != data.Nonce {
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if pending == 0 {
+			return
+		}
+
+		members = append(members, c.Members...)
+		presences = append(presences, c.Presences...)
+
+		received[c.GuildID]++
+		if received[c.GuildID] == c.ChunkCount {
+			pending--
+			if pending == 0 {
+				close(done)
+			}
+		}
+	})
+	defer rm()
+
+	if err := s.Gateway.RequestGuildMembersCtx(ctx, data); err != nil {
+		return nil, nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-done:
+	}
+
+	if data.Query == "" && len(data.UserIDs) == 0 && data.Limit == 0 {
+		for _, id := range data.GuildID {
+			s.chunkedGuilds.Add(id)
+		}
+	}
+
+	return members, presences, nil
+}
+
+// trackChunked updates the set of guilds whose members are completely
+// stored in the Cabinet, based on the passed gateway event.
+func (s *State) trackChunked(e interface{}) {
+	switch e := e.(type) {
+	case *gateway.ReadyEvent:
+		s.chunkedGuilds.Clear()
+	case *gateway.GuildCreateEvent:
+		// small guilds are sent with all of their members
+		if !e.Unavailable && len(e.Members) >= int(e.MemberCount) {
+			s.chunkedGuilds.Add(e.ID)
+		}
+	case *gateway.GuildDeleteEvent:
+		s.chunkedGuilds.Delete(e.ID)
+	}
+}
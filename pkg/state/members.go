@@ -0,0 +1,71 @@
+package state
+
+import (
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/pkg/errors"
+)
+
+// IterMembers calls f with every member of the guild with the passed id,
+// until f returns false.
+//
+// If all members of the guild are stored in the Cabinet, because the guild
+// was small enough to be sent with all of its members, or its members were
+// requested using MembersOf, WarmGuild or DeferLargeGuildCaching, the
+// members are read from the Cabinet.
+// Otherwise, the members are fetched from the API page by page, and stored
+// in the Cabinet, if member caching is enabled.
+//
+// Note that cabinets that evict members, such as those created by
+// cabinet.Limit, may cause IterMembers to miss members.
+func (s *State) IterMembers(guildID discord.GuildID, f func(discord.Member) bool) error {
+	if s.chunkedGuilds.Contains(guildID) && !s.noCaching.Has(ResourceMembers) {
+		members, err := s.Cabinet.Members(guildID)
+		if err == nil {
+			for _, m := range members {
+				if !f(m) {
+					return nil
+				}
+			}
+
+			return nil
+		}
+	}
+
+	cache := !s.noCaching.Has(ResourceMembers) && s.Gateway.HasIntents(gateway.IntentGuildMembers)
+
+	var after discord.UserID
+
+	for {
+		members, err := s.Client.MembersAfter(guildID, after, api.MaxMemberFetchLimit)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range members {
+			if cache {
+				if err := s.Cabinet.MemberSet(guildID, m); err != nil {
+					return errors.Wrap(err, "failed to store member")
+				}
+			}
+
+			if !f(m) {
+				return nil
+			}
+		}
+
+		if len(members) < api.MaxMemberFetchLimit {
+			break
+		}
+
+		after = members[len(members)-1].User.ID
+	}
+
+	// only with the intent, the cabinet is kept up to date
+	if cache {
+		s.chunkedGuilds.Add(guildID)
+	}
+
+	return nil
+}
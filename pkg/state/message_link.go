@@ -0,0 +1,122 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+var (
+	// ErrInvalidMessageLink gets returned by ResolveMessageLink, if the passed
+	// link is not a valid message link.
+	ErrInvalidMessageLink = errors.New("state: invalid message link")
+	// ErrMessageLinkMismatch gets returned by ResolveMessageLink, if the
+	// channel of the link is not located in the guild of the link.
+	ErrMessageLinkMismatch = errors.New("state: the channel of the message link is not in the link's guild")
+)
+
+// ResolvedMessageLink is the result of a call to ResolveMessageLink.
+type ResolvedMessageLink struct {
+	// Message is the linked message.
+	Message *discord.Message
+	// Channel is the channel the message was sent in.
+	Channel *discord.Channel
+	// Guild is the guild the message was sent in.
+	// It is nil, if the message was sent in a private channel.
+	Guild *discord.Guild
+}
+
+// ResolveMessageLink parses the passed message link, as created by Discord's
+// 'Copy Message Link' option, and returns the linked message alongside its
+// channel and guild.
+//
+// The message, channel, and guild are taken from the Cabinet, if possible,
+// and fetched from the API otherwise.
+//
+// If the link is malformed, ErrInvalidMessageLink will be returned, and if
+// the channel of the message does not belong to the guild of the link,
+// ErrMessageLinkMismatch.
+func (s *State) ResolveMessageLink(ctx context.Context, link string) (*ResolvedMessageLink, error) {
+	guildID, channelID, messageID, err := parseMessageLink(link)
+	if err != nil {
+		return nil, err
+	}
+
+	s = s.WithContext(ctx)
+
+	c, err := s.Channel(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.GuildID != guildID {
+		return nil, ErrMessageLinkMismatch
+	}
+
+	r := &ResolvedMessageLink{Channel: c}
+
+	if guildID.IsValid() {
+		r.Guild, err = s.Guild(guildID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.Message, err = s.Message(channelID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// parseMessageLink parses the passed message link.
+// If the link points to a message in a private channel, guildID will be 0.
+func parseMessageLink(link string) (
+	guildID discord.GuildID, channelID discord.ChannelID, messageID discord.MessageID, err error,
+) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return 0, 0, 0, ErrInvalidMessageLink
+	}
+
+	switch u.Host {
+	case "discord.com", "ptb.discord.com", "canary.discord.com", "discordapp.com",
+		"ptb.discordapp.com", "canary.discordapp.com":
+	default:
+		return 0, 0, 0, ErrInvalidMessageLink
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "channels" {
+		return 0, 0, 0, ErrInvalidMessageLink
+	}
+
+	if parts[1] != "@me" {
+		sf, err := discord.ParseSnowflake(parts[1])
+		if err != nil || !sf.IsValid() {
+			return 0, 0, 0, ErrInvalidMessageLink
+		}
+
+		guildID = discord.GuildID(sf)
+	}
+
+	sf, err := discord.ParseSnowflake(parts[2])
+	if err != nil || !sf.IsValid() {
+		return 0, 0, 0, ErrInvalidMessageLink
+	}
+
+	channelID = discord.ChannelID(sf)
+
+	sf, err = discord.ParseSnowflake(parts[3])
+	if err != nil || !sf.IsValid() {
+		return 0, 0, 0, ErrInvalidMessageLink
+	}
+
+	messageID = discord.MessageID(sf)
+
+	return guildID, channelID, messageID, nil
+}
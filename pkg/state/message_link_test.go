@@ -0,0 +1,108 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessageLink(t *testing.T) {
+	successCases := []struct {
+		name      string
+		link      string
+		guildID   discord.GuildID
+		channelID discord.ChannelID
+		messageID discord.MessageID
+	}{
+		{
+			name:      "guild",
+			link:      "https://discord.com/channels/1/2/3",
+			guildID:   1,
+			channelID: 2,
+			messageID: 3,
+		},
+		{
+			name:      "private channel",
+			link:      "https://discord.com/channels/@me/2/3",
+			channelID: 2,
+			messageID: 3,
+		},
+		{
+			name:      "canary",
+			link:      "https://canary.discordapp.com/channels/1/2/3/",
+			guildID:   1,
+			channelID: 2,
+			messageID: 3,
+		},
+	}
+
+	for _, c := range successCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			guildID, channelID, messageID, err := parseMessageLink(c.link)
+			require.NoError(t, err)
+
+			assert.Equal(t, c.guildID, guildID)
+			assert.Equal(t, c.channelID, channelID)
+			assert.Equal(t, c.messageID, messageID)
+		})
+	}
+
+	failureCases := []struct {
+		name string
+		link string
+	}{
+		{name: "other host", link: "https://example.com/channels/1/2/3"},
+		{name: "missing message", link: "https://discord.com/channels/1/2"},
+		{name: "not channels", link: "https://discord.com/guilds/1/2/3"},
+		{name: "invalid id", link: "https://discord.com/channels/1/abc/3"},
+		{name: "zero id", link: "https://discord.com/channels/1/2/0"},
+	}
+
+	for _, c := range failureCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			_, _, _, err := parseMessageLink(c.link)
+			assert.Equal(t, ErrInvalidMessageLink, err)
+		})
+	}
+}
+
+func TestState_ResolveMessageLink(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s := NewMocker(t)
+
+		c := discord.Channel{ID: 2, GuildID: 1}
+		m.Channel(c)
+
+		g := discord.Guild{ID: 1}
+		m.Guild(g)
+
+		msg := discord.Message{ID: 3, ChannelID: 2, GuildID: 1}
+		m.Message(msg)
+		// State.Message fetches the channel again, to fill the GuildID of the
+		// message
+		m.Channel(c)
+
+		r, err := s.ResolveMessageLink(context.Background(), "https://discord.com/channels/1/2/3")
+		require.NoError(t, err)
+
+		assert.Equal(t, c.ID, r.Channel.ID)
+		assert.Equal(t, g.ID, r.Guild.ID)
+		assert.Equal(t, msg.ID, r.Message.ID)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		m, s := NewMocker(t)
+
+		m.Channel(discord.Channel{ID: 2, GuildID: 4})
+
+		_, err := s.ResolveMessageLink(context.Background(), "https://discord.com/channels/1/2/3")
+		assert.Equal(t, ErrMessageLinkMismatch, err)
+	})
+}
@@ -0,0 +1,127 @@
+package state
+
+import (
+	"regexp"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+// channelMentionRegexp matches channel mentions and captures the channel id.
+var channelMentionRegexp = regexp.MustCompile(`<#(\d+)>`)
+
+// MentionedMembers returns the members mentioned in the message.
+// Members are taken from the event, if possible, and otherwise from the
+// Cabinet or the API.
+//
+// If the message was sent in a private channel, MentionedMembers returns nil.
+func (e *MessageCreateEvent) MentionedMembers(s *State) ([]discord.Member, error) {
+	return mentionedMembers(s, &e.Message)
+}
+
+// MentionedRoles returns the roles mentioned in the message.
+// Roles are taken from the Cabinet, if possible, and otherwise from the API.
+func (e *MessageCreateEvent) MentionedRoles(s *State) ([]discord.Role, error) {
+	return mentionedRoles(s, &e.Message)
+}
+
+// MentionedChannels returns the channels mentioned in the content of the
+// message.
+// Channels are taken from the Cabinet, if possible, and otherwise from the
+// API.
+func (e *MessageCreateEvent) MentionedChannels(s *State) ([]discord.Channel, error) {
+	return mentionedChannels(s, &e.Message)
+}
+
+// MentionedMembers returns the members mentioned in the message.
+// Members are taken from the event, if possible, and otherwise from the
+// Cabinet or the API.
+//
+// If the message was sent in a private channel, MentionedMembers returns nil.
+func (e *MessageUpdateEvent) MentionedMembers(s *State) ([]discord.Member, error) {
+	return mentionedMembers(s, &e.Message)
+}
+
+// MentionedRoles returns the roles mentioned in the message.
+// Roles are taken from the Cabinet, if possible, and otherwise from the API.
+func (e *MessageUpdateEvent) MentionedRoles(s *State) ([]discord.Role, error) {
+	return mentionedRoles(s, &e.Message)
+}
+
+// MentionedChannels returns the channels mentioned in the content of the
+// message.
+// Channels are taken from the Cabinet, if possible, and otherwise from the
+// API.
+func (e *MessageUpdateEvent) MentionedChannels(s *State) ([]discord.Channel, error) {
+	return mentionedChannels(s, &e.Message)
+}
+
+func mentionedMembers(s *State, m *discord.Message) ([]discord.Member, error) {
+	if !m.GuildID.IsValid() {
+		return nil, nil
+	}
+
+	members := make([]discord.Member, 0, len(m.Mentions))
+
+	for _, u := range m.Mentions {
+		if u.Member != nil {
+			member := *u.Member
+			member.User = u.User
+
+			members = append(members, member)
+			continue
+		}
+
+		member, err := s.Member(m.GuildID, u.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, *member)
+	}
+
+	return members, nil
+}
+
+func mentionedRoles(s *State, m *discord.Message) ([]discord.Role, error) {
+	roles := make([]discord.Role, 0, len(m.MentionRoleIDs))
+
+	for _, id := range m.MentionRoleIDs {
+		r, err := s.Role(m.GuildID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, *r)
+	}
+
+	return roles, nil
+}
+
+func mentionedChannels(s *State, m *discord.Message) ([]discord.Channel, error) {
+	matches := channelMentionRegexp.FindAllStringSubmatch(m.Content, -1)
+
+	channels := make([]discord.Channel, 0, len(matches))
+	seen := make(map[discord.Snowflake]struct{}, len(matches))
+
+	for _, match := range matches {
+		sf, err := discord.ParseSnowflake(match[1])
+		if err != nil {
+			continue
+		}
+
+		if _, ok := seen[sf]; ok {
+			continue
+		}
+
+		seen[sf] = struct{}{}
+
+		c, err := s.Channel(discord.ChannelID(sf))
+		if err != nil {
+			return nil, err
+		}
+
+		channels = append(channels, *c)
+	}
+
+	return channels, nil
+}
@@ -0,0 +1,63 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCreateEvent_MentionedMembers(t *testing.T) {
+	t.Run("private channel", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		e := newMessageCreateEvent()
+		e.Mentions = []discord.GuildUser{{User: discord.User{ID: 1}}}
+
+		members, err := e.MentionedMembers(s)
+		require.NoError(t, err)
+		assert.Nil(t, members)
+	})
+
+	t.Run("guild", func(t *testing.T) {
+		m, s := NewMocker(t)
+
+		fetched := discord.Member{User: discord.User{ID: 2}, Nick: "def"}
+		m.Member(1, fetched)
+
+		e := newMessageCreateEvent()
+		e.GuildID = 1
+		e.Mentions = []discord.GuildUser{
+			{User: discord.User{ID: 1}, Member: &discord.Member{Nick: "abc"}},
+			{User: discord.User{ID: 2}},
+		}
+
+		members, err := e.MentionedMembers(s)
+		require.NoError(t, err)
+
+		require.Len(t, members, 2)
+		// taken from the event
+		assert.Equal(t, discord.UserID(1), members[0].User.ID)
+		assert.Equal(t, "abc", members[0].Nick)
+		// fetched
+		assert.Equal(t, fetched.Nick, members[1].Nick)
+	})
+}
+
+func TestMessageCreateEvent_MentionedChannels(t *testing.T) {
+	m, s := NewMocker(t)
+
+	m.Channel(discord.Channel{ID: 123})
+	m.Channel(discord.Channel{ID: 456})
+
+	e := newMessageCreateEvent()
+	e.Content = "<#123> <#456> <#123> #789 <@1>"
+
+	channels, err := e.MentionedChannels(s)
+	require.NoError(t, err)
+
+	require.Len(t, channels, 2)
+	assert.Equal(t, discord.ChannelID(123), channels[0].ID)
+	assert.Equal(t, discord.ChannelID(456), channels[1].ID)
+}
@@ -0,0 +1,273 @@
+package state
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+// Metrics is the interface used by the EventHandler to report dispatch
+// metrics.
+//
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// EventDispatched gets called every time handlers are about to be called
+	// for an event.
+	// eventType is the name of the event's type, e.g.
+	// "MessageCreateEvent".
+	//
+	// Events that cause a situation-specific event to be dispatched, such
+	// as GuildCreateEvents, are reported twice: once for the event itself,
+	// and once for the situation-specific event.
+	EventDispatched(eventType string, e interface{})
+	// HandlerExecuted gets called every time a handler func returned.
+	// handlerName is the name of the handler func as reported by
+	// runtime.FuncForPC, d the time it took the handler to execute, and err
+	// the error returned by it, if any.
+	//
+	// Channel handlers are not reported.
+	HandlerExecuted(eventType, handlerName string, e interface{}, d time.Duration, err error)
+}
+
+// DurationBuckets are the upper bounds of the buckets of the handler
+// execution duration histograms collected by Stats.
+var DurationBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+type (
+	// Stats is a Metrics implementation that collects the metrics in
+	// memory.
+	Stats struct {
+		events   map[string]uint64
+		handlers map[string]*HandlerStats
+
+		// maxGuilds is the maximum number of guilds tracked.
+		// If it is 0, guilds are not tracked.
+		maxGuilds int
+		guilds    map[discord.GuildID]*GuildStats
+
+		mutex sync.Mutex
+	}
+
+	// StatsSnapshot is a snapshot of the metrics collected by Stats.
+	StatsSnapshot struct {
+		// Events are the number of dispatches per event type.
+		Events map[string]uint64
+		// Handlers are the statistics of the handlers, keyed by the name
+		// of the handler func.
+		Handlers map[string]HandlerStats
+		// Guilds are the statistics of the tracked guilds.
+		// It is nil, if guilds aren't tracked.
+		Guilds map[discord.GuildID]GuildStats
+	}
+
+	// GuildStats are the statistics of a single guild.
+	GuildStats struct {
+		// Events is the number of events dispatched for the guild.
+		Events uint64
+		// Errors is the number of errors returned by handlers of the guild's
+		// events.
+		Errors uint64
+	}
+
+	// HandlerStats are the statistics of a single handler.
+	HandlerStats struct {
+		// Calls is the number of times the handler was called.
+		Calls uint64
+		// Errors is the number of times the handler returned an error.
+		Errors uint64
+		// Total is the total time the handler spent executing.
+		Total time.Duration
+		// Buckets is the histogram of the handler's execution durations.
+		// Buckets[i] is the number of executions that took at most
+		// DurationBuckets[i], and longer than DurationBuckets[i-1].
+		// The last element counts all executions that took longer than the
+		// last element of DurationBuckets.
+		Buckets []uint64
+	}
+)
+
+var _ Metrics = new(Stats)
+
+// NewStats creates a new Stats collector.
+func NewStats() *Stats {
+	return &Stats{
+		events:   make(map[string]uint64),
+		handlers: make(map[string]*HandlerStats),
+	}
+}
+
+// NewStatsWithGuilds creates a new Stats collector, that additionally
+// collects the event and error counts of the maxGuilds guilds with the most
+// events.
+//
+// Once maxGuilds guilds are tracked, the guild with the fewest events is
+// replaced, when an event of an untracked guild is dispatched.
+// The counts of the new guild start at the counts of the replaced guild, so
+// that counts are overestimated rather than underestimated, and guilds that
+// produce a lot of events are guaranteed to be tracked.
+func NewStatsWithGuilds(maxGuilds int) *Stats {
+	s := NewStats()
+	s.maxGuilds = maxGuilds
+	s.guilds = make(map[discord.GuildID]*GuildStats, maxGuilds)
+
+	return s
+}
+
+func (s *Stats) EventDispatched(eventType string, e interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.events[eventType]++
+
+	if gs := s.guild(e); gs != nil {
+		gs.Events++
+	}
+}
+
+// guild returns the GuildStats of the guild the passed event belongs to.
+// If guilds aren't tracked or the event doesn't belong to a guild, nil is
+// returned.
+//
+// s.mutex must be held.
+func (s *Stats) guild(e interface{}) *GuildStats {
+	if s.maxGuilds <= 0 {
+		return nil
+	}
+
+	guildID := eventGuildID(e)
+	if !guildID.IsValid() {
+		return nil
+	}
+
+	if gs, ok := s.guilds[guildID]; ok {
+		return gs
+	}
+
+	gs := new(GuildStats)
+
+	if len(s.guilds) >= s.maxGuilds {
+		var (
+			minID discord.GuildID
+			min   *GuildStats
+		)
+
+		for id, gs := range s.guilds {
+			if min == nil || gs.Events < min.Events {
+				minID, min = id, gs
+			}
+		}
+
+		delete(s.guilds, minID)
+		*gs = *min
+	}
+
+	s.guilds[guildID] = gs
+	return gs
+}
+
+func (s *Stats) HandlerExecuted(_, handlerName string, e interface{}, d time.Duration, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hs, ok := s.handlers[handlerName]
+	if !ok {
+		hs = &HandlerStats{Buckets: make([]uint64, len(DurationBuckets)+1)}
+		s.handlers[handlerName] = hs
+	}
+
+	hs.Calls++
+	hs.Total += d
+
+	if err != nil && !errors.Is(err, Filtered) {
+		hs.Errors++
+
+		if s.maxGuilds > 0 {
+			if gs, ok := s.guilds[eventGuildID(e)]; ok {
+				gs.Errors++
+			}
+		}
+	}
+
+	i := 0
+	for i < len(DurationBuckets) && d > DurationBuckets[i] {
+		i++
+	}
+
+	hs.Buckets[i]++
+}
+
+// Snapshot returns a copy of the metrics collected so far.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snap := StatsSnapshot{
+		Events:   make(map[string]uint64, len(s.events)),
+		Handlers: make(map[string]HandlerStats, len(s.handlers)),
+	}
+
+	for t, n := range s.events {
+		snap.Events[t] = n
+	}
+
+	for name, hs := range s.handlers {
+		cp := *hs
+		cp.Buckets = append([]uint64(nil), hs.Buckets...)
+
+		snap.Handlers[name] = cp
+	}
+
+	if s.guilds != nil {
+		snap.Guilds = make(map[discord.GuildID]GuildStats, len(s.guilds))
+
+		for id, gs := range s.guilds {
+			snap.Guilds[id] = *gs
+		}
+	}
+
+	return snap
+}
+
+// Reset resets all collected metrics.
+func (s *Stats) Reset() {
+	s.mutex.Lock()
+	s.events = make(map[string]uint64)
+	s.handlers = make(map[string]*HandlerStats)
+
+	if s.guilds != nil {
+		s.guilds = make(map[discord.GuildID]*GuildStats, s.maxGuilds)
+	}
+
+	s.mutex.Unlock()
+}
+
+// TopGuilds returns the ids of the n tracked guilds with the most events,
+// sorted by descending number of events.
+func (snap StatsSnapshot) TopGuilds(n int) []discord.GuildID {
+	ids := make([]discord.GuildID, 0, len(snap.Guilds))
+	for id := range snap.Guilds {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return snap.Guilds[ids[i]].Events > snap.Guilds[ids[j]].Events
+	})
+
+	if n < len(ids) {
+		ids = ids[:n]
+	}
+
+	return ids
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	_, s := NewMocker(t)
+	s.ErrorHandler = func(error) {}
+
+	stats := NewStats()
+	s.Metrics = stats
+
+	s.MustAddHandler(func(*State, *MessageCreateEvent) error { return errors.New("abc") })
+	s.MustAddHandler(func(*State, *MessageCreateEvent) error { return Filtered })
+
+	s.Call(newMessageCreateEvent())
+	s.wg.Wait()
+
+	snap := stats.Snapshot()
+
+	assert.Equal(t, map[string]uint64{"MessageCreateEvent": 1}, snap.Events)
+	assert.Nil(t, snap.Guilds)
+
+	require.Len(t, snap.Handlers, 2)
+
+	var calls, errs, fast uint64
+
+	for _, hs := range snap.Handlers {
+		calls += hs.Calls
+		errs += hs.Errors
+		fast += hs.Buckets[0]
+
+		assert.Len(t, hs.Buckets, len(DurationBuckets)+1)
+	}
+
+	assert.Equal(t, uint64(2), calls)
+	// filtered events aren't counted as errors
+	assert.Equal(t, uint64(1), errs)
+	assert.Equal(t, uint64(2), fast)
+
+	stats.Reset()
+	assert.Empty(t, stats.Snapshot().Events)
+}
+
+func TestStats_HandlerExecuted(t *testing.T) {
+	stats := NewStats()
+
+	stats.HandlerExecuted("", "abc", nil, 0, nil)
+	stats.HandlerExecuted("", "abc", nil, 7*time.Millisecond, nil)
+	stats.HandlerExecuted("", "abc", nil, time.Minute, nil)
+
+	hs := stats.Snapshot().Handlers["abc"]
+
+	assert.Equal(t, uint64(3), hs.Calls)
+	assert.Equal(t, time.Minute+7*time.Millisecond, hs.Total)
+	assert.Equal(t, []uint64{1, 0, 1, 0, 0, 0, 0, 0, 1}, hs.Buckets)
+}
+
+func TestNewStatsWithGuilds(t *testing.T) {
+	stats := NewStatsWithGuilds(2)
+
+	dispatch := func(guildID discord.GuildID, n int) {
+		for i := 0; i < n; i++ {
+			e := newMessageCreateEvent()
+			e.GuildID = guildID
+
+			stats.EventDispatched("MessageCreateEvent", e)
+		}
+	}
+
+	dispatch(1, 3)
+	dispatch(2, 1)
+
+	e := newMessageCreateEvent()
+	e.GuildID = 1
+	stats.HandlerExecuted("MessageCreateEvent", "abc", e, 0, errors.New("abc"))
+
+	// replaces 2, which has the fewest events
+	dispatch(3, 1)
+
+	// events of private channels aren't tracked
+	dispatch(0, 1)
+
+	snap := stats.Snapshot()
+
+	assert.Equal(t, map[discord.GuildID]GuildStats{
+		1: {Events: 3, Errors: 1},
+		// inherits the count of 2
+		3: {Events: 2},
+	}, snap.Guilds)
+
+	assert.Equal(t, []discord.GuildID{1}, snap.TopGuilds(1))
+	assert.Equal(t, []discord.GuildID{1, 3}, snap.TopGuilds(5))
+}
@@ -0,0 +1,53 @@
+package state
+
+// MiddlewareChain is a reusable list of middlewares.
+//
+// A MiddlewareChain can be passed to AddHandler and its variants in place of a
+// middleware, in which case all middlewares of the chain will be added in
+// order.
+// Chains may also contain other chains.
+//
+// Like all middlewares, the middlewares of a chain must either be interface{}
+// or Base middlewares, or have the same type as the handler they are used
+// with.
+type MiddlewareChain []interface{}
+
+// NewMiddlewareChain creates a new MiddlewareChain from the passed
+// middlewares.
+func NewMiddlewareChain(middlewares ...interface{}) MiddlewareChain {
+	return append(MiddlewareChain(nil), middlewares...)
+}
+
+// With returns a copy of the MiddlewareChain with the passed middlewares
+// appended.
+// The original MiddlewareChain remains unchanged.
+func (c MiddlewareChain) With(middlewares ...interface{}) MiddlewareChain {
+	cp := make(MiddlewareChain, len(c), len(c)+len(middlewares))
+	copy(cp, c)
+
+	return append(cp, middlewares...)
+}
+
+// flattenMiddlewares replaces all MiddlewareChains in the passed middlewares
+// with the middlewares they contain.
+func flattenMiddlewares(middlewares []interface{}) []interface{} {
+	var flat []interface{}
+
+	for i, m := range middlewares {
+		if c, ok := m.(MiddlewareChain); ok {
+			if flat == nil { // lazily copy, so we don't allocate if there are no chains
+				flat = append(make([]interface{}, 0, len(middlewares)+len(c)), middlewares[:i]...)
+			}
+
+			flat = append(flat, flattenMiddlewares(c)...)
+		} else if flat != nil {
+			flat = append(flat, m)
+		}
+	}
+
+	if flat == nil {
+		return middlewares
+	}
+
+	return flat
+}
@@ -0,0 +1,56 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareChain_With(t *testing.T) {
+	c := NewMiddlewareChain(1, 2)
+
+	a := c.With(3)
+	b := c.With(4)
+
+	assert.Equal(t, MiddlewareChain{1, 2}, c)
+	assert.Equal(t, MiddlewareChain{1, 2, 3}, a)
+	assert.Equal(t, MiddlewareChain{1, 2, 4}, b)
+}
+
+func TestFlattenMiddlewares(t *testing.T) {
+	testCases := []struct {
+		name        string
+		middlewares []interface{}
+		expect      []interface{}
+	}{
+		{
+			name:        "no chains",
+			middlewares: []interface{}{1, 2},
+			expect:      []interface{}{1, 2},
+		},
+		{
+			name:        "chain",
+			middlewares: []interface{}{1, NewMiddlewareChain(2, 3), 4},
+			expect:      []interface{}{1, 2, 3, 4},
+		},
+		{
+			name: "nested chains",
+			middlewares: []interface{}{
+				NewMiddlewareChain(1, NewMiddlewareChain(2, NewMiddlewareChain(3)), 4),
+				5,
+				NewMiddlewareChain(),
+				NewMiddlewareChain(6),
+			},
+			expect: []interface{}{1, 2, 3, 4, 5, 6},
+		},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			actual := flattenMiddlewares(c.middlewares)
+			assert.Equal(t, c.expect, actual)
+		})
+	}
+}
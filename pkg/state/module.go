@@ -0,0 +1,85 @@
+package state
+
+import (
+	"context"
+	"sync"
+)
+
+// Module is a set of handlers and middlewares, whose lifecycle is managed by
+// the State.
+//
+// A module is used for a single session:
+// When the State is closed, the handlers of the module are removed, and the
+// module is closed.
+// To use a module again after reopening the State, it must be passed to Use
+// again.
+type Module interface {
+	// Register registers the handlers and middlewares of the module using
+	// the passed HandlerGroup.
+	// All handlers added through the group will be removed, when the module
+	// is closed, or if Register returns an error.
+	Register(g *HandlerGroup) error
+	// Close closes the module.
+	// It is called when the State is closed, after all handlers have
+	// finished executing and the handlers of the module were removed.
+	Close(ctx context.Context) error
+}
+
+// moduleList is the list of modules used by a State.
+type moduleList struct {
+	modules []module
+	mutex   sync.Mutex
+}
+
+// module is a Module and the HandlerGroup it registered its handlers with.
+type module struct {
+	Module
+	group *HandlerGroup
+}
+
+// Use registers the passed modules.
+// When the State is closed, the modules will be closed in the reverse order
+// they were added in.
+//
+// If one of the modules fails to register, Use returns the error and doesn't
+// register the remaining modules.
+// The handlers the failed module added are removed, however, modules that
+// registered successfully will still be closed with the State.
+func (s *State) Use(modules ...Module) error {
+	s.modules.mutex.Lock()
+	defer s.modules.mutex.Unlock()
+
+	for _, m := range modules {
+		g := s.Group()
+
+		if err := m.Register(g); err != nil {
+			g.RemoveAll()
+			return err
+		}
+
+		s.modules.modules = append(s.modules.modules, module{Module: m, group: g})
+	}
+
+	return nil
+}
+
+// closeModules removes the handlers of all modules and closes them in reverse
+// order, and returns the first error that occurred.
+// All modules are closed, even if one of them returns an error.
+func (s *State) closeModules(ctx context.Context) (err error) {
+	s.modules.mutex.Lock()
+	defer s.modules.mutex.Unlock()
+
+	for i := len(s.modules.modules) - 1; i >= 0; i-- {
+		m := s.modules.modules[i]
+		m.group.RemoveAll()
+
+		if cerr := m.Close(ctx); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	s.modules.modules = nil
+
+	return err
+}
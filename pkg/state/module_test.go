@@ -0,0 +1,126 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testModule struct {
+	name        string
+	registerErr error
+	closeErr    error
+
+	calls  *int32
+	closed *[]string
+}
+
+func (m *testModule) Register(g *HandlerGroup) error {
+	g.MustAddHandler(func(_ *State, _ *MessageCreateEvent) {
+		atomic.AddInt32(m.calls, 1)
+	})
+
+	return m.registerErr
+}
+
+func (m *testModule) Close(context.Context) error {
+	*m.closed = append(*m.closed, m.name)
+	return m.closeErr
+}
+
+func newMessageCreateEvent() *MessageCreateEvent {
+	return &MessageCreateEvent{
+		MessageCreateEvent: &gateway.MessageCreateEvent{Message: discord.Message{ID: 1, ChannelID: 1}},
+		Base:               NewBase(),
+	}
+}
+
+func TestState_Use(t *testing.T) {
+	t.Run("register error", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		var (
+			calls  int32
+			closed []string
+		)
+
+		registerErr := errors.New("abc")
+
+		err := s.Use(
+			&testModule{name: "a", calls: &calls, closed: &closed},
+			&testModule{name: "b", registerErr: registerErr, calls: &calls, closed: &closed},
+			&testModule{name: "c", calls: &calls, closed: &closed},
+		)
+		assert.Equal(t, registerErr, err)
+
+		s.Call(newMessageCreateEvent())
+
+		// only the handler of a is still registered
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 },
+			time.Second, time.Millisecond)
+		assert.Never(t, func() bool { return atomic.LoadInt32(&calls) > 1 },
+			50*time.Millisecond, time.Millisecond)
+
+		require.NoError(t, s.closeModules(context.Background()))
+		assert.Equal(t, []string{"a"}, closed)
+	})
+}
+
+func TestState_closeModules(t *testing.T) {
+	t.Run("removes handlers", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		var (
+			calls  int32
+			closed []string
+		)
+
+		require.NoError(t, s.Use(&testModule{name: "a", calls: &calls, closed: &closed}))
+
+		s.Call(newMessageCreateEvent())
+
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 },
+			time.Second, time.Millisecond)
+
+		require.NoError(t, s.closeModules(context.Background()))
+
+		s.Call(newMessageCreateEvent())
+
+		assert.Never(t, func() bool { return atomic.LoadInt32(&calls) > 1 },
+			50*time.Millisecond, time.Millisecond)
+	})
+
+	t.Run("reverse order", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		var (
+			calls  int32
+			closed []string
+		)
+
+		closeErr := errors.New("abc")
+
+		require.NoError(t, s.Use(
+			&testModule{name: "a", calls: &calls, closed: &closed},
+			&testModule{name: "b", closeErr: closeErr, calls: &calls, closed: &closed},
+			&testModule{name: "c", calls: &calls, closed: &closed},
+		))
+
+		err := s.closeModules(context.Background())
+		assert.Equal(t, closeErr, err)
+		assert.Equal(t, []string{"c", "b", "a"}, closed)
+
+		// modules are only closed once
+		closed = nil
+
+		require.NoError(t, s.closeModules(context.Background()))
+		assert.Empty(t, closed)
+	})
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// fatalCloseCodes are the close codes of the gateway, after which opening
+// won't succeed, unless the configuration of the State is changed.
+var fatalCloseCodes = map[int]struct{}{
+	4004: {}, // authentication failed
+	4010: {}, // invalid shard
+	4011: {}, // sharding required
+	4012: {}, // invalid API version
+	4013: {}, // invalid intents
+	4014: {}, // disallowed intents
+}
+
+// OpenRetryPolicy is the policy used to retry failed attempts to open the
+// gateway.
+type OpenRetryPolicy struct {
+	// MaxRetries is the maximum amount of times opening is retried.
+	MaxRetries int
+	// BaseDelay is the maximum delay before the first retry.
+	// The maximum delay is doubled with every retry, until it reaches
+	// MaxDelay.
+	// The actual delay is randomly chosen between 0 and the maximum delay.
+	BaseDelay time.Duration
+	// MaxDelay is the upper bound for the delay between two retries.
+	MaxDelay time.Duration
+
+	// OnRetry, if not nil, gets called before opening is retried.
+	// err is the error of the failed attempt, attempt the number of the
+	// upcoming retry, starting at 1, and wait the time until the retry.
+	OnRetry func(err error, attempt int, wait time.Duration)
+}
+
+// SetOpenRetryPolicy sets the policy used by Open to retry failed attempts
+// to open the gateway, e.g. because of transient network errors.
+// Attempts that failed because of an invalid token, invalid or disallowed
+// intents, or an invalid sharding configuration are not retried.
+//
+// SetOpenRetryPolicy must be called before Open.
+func (s *State) SetOpenRetryPolicy(p OpenRetryPolicy) {
+	s.openRetry = &p
+}
+
+// openGateway opens the gateway, retrying failed attempts according to the
+// OpenRetryPolicy of the State.
+func (s *State) openGateway(ctx context.Context) error {
+	for attempt := 1; ; attempt++ {
+		err := s.openGatewayOnce(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if s.openRetry == nil || attempt > s.openRetry.MaxRetries || !isTransientOpenError(err) {
+			return err
+		}
+
+		// reset the websocket, in case it was dialed
+		_ = s.Gateway.Close()
+
+		wait := backoff(s.openRetry.BaseDelay, s.openRetry.MaxDelay, attempt)
+
+		if s.openRetry.OnRetry != nil {
+			s.openRetry.OnRetry(err, attempt, wait)
+		}
+
+		t := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// openGatewayOnce makes a single attempt to open the gateway, limited by the
+// WSTimeout of the gateway.
+func (s *State) openGatewayOnce(ctx context.Context) error {
+	if s.Gateway.WSTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, s.Gateway.WSTimeout)
+		defer cancel()
+	}
+
+	return s.Gateway.OpenContext(ctx)
+}
+
+// isTransientOpenError checks if opening the gateway may succeed, when
+// retrying after the passed error.
+func isTransientOpenError(err error) bool {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		_, fatal := fatalCloseCodes[closeErr.Code]
+		return !fatal
+	}
+
+	var httpErr *httputil.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Status != http.StatusUnauthorized && httpErr.Status != http.StatusForbidden
+	}
+
+	return true
+}
@@ -0,0 +1,65 @@
+package state
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/gorilla/websocket"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransientOpenError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{
+			name:   "unknown error",
+			err:    errors.New("abc"),
+			expect: true,
+		},
+		{
+			name:   "transient close code",
+			err:    &websocket.CloseError{Code: 4000},
+			expect: true,
+		},
+		{
+			name:   "authentication failed",
+			err:    &websocket.CloseError{Code: 4004},
+			expect: false,
+		},
+		{
+			name:   "disallowed intents",
+			err:    pkgerrors.Wrap(&websocket.CloseError{Code: 4014}, "failed to open gateway"),
+			expect: false,
+		},
+		{
+			name:   "server error",
+			err:    &httputil.HTTPError{Status: http.StatusBadGateway},
+			expect: true,
+		},
+		{
+			name:   "unauthorized",
+			err:    &httputil.HTTPError{Status: http.StatusUnauthorized},
+			expect: false,
+		},
+		{
+			name:   "forbidden",
+			err:    &httputil.HTTPError{Status: http.StatusForbidden},
+			expect: false,
+		},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			actual := isTransientOpenError(c.err)
+			assert.Equal(t, c.expect, actual)
+		})
+	}
+}
@@ -9,6 +9,8 @@ type PresenceUpdateEvent struct {
 	*gateway.PresenceUpdateEvent
 	*Base
 
+	// Old is the presence before the update.
+	// It is always nil, if presence caching is disabled.
 	Old *gateway.Presence
 }
 
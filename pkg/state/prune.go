@@ -0,0 +1,177 @@
+package state
+
+import (
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/pkg/errors"
+)
+
+// PruneMessages removes all messages older than the passed duration from the
+// Cabinet, and returns the number of removed messages.
+// The age of a message is determined by its id.
+func (s *State) PruneMessages(olderThan time.Duration) (int, error) {
+	channels, err := s.cachedChannels()
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(-olderThan)
+	pruned := 0
+
+	for _, c := range channels {
+		n, err := s.pruneMessages(c.ID, func(m discord.Message) bool {
+			return m.ID.Time().Before(deadline)
+		})
+		pruned += n
+		if err != nil {
+			return pruned, err
+		}
+	}
+
+	return pruned, nil
+}
+
+// PruneChannelMessages removes all messages sent in the channel with the
+// passed id from the Cabinet, and returns the number of removed messages.
+func (s *State) PruneChannelMessages(channelID discord.ChannelID) (int, error) {
+	return s.pruneMessages(channelID, func(discord.Message) bool { return true })
+}
+
+// PruneMembers removes all members of the guild with the passed id from the
+// Cabinet, and returns the number of removed members.
+func (s *State) PruneMembers(guildID discord.GuildID) (int, error) {
+	s.chunkedGuilds.Delete(guildID)
+
+	members, err := s.Cabinet.Members(guildID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil
+		}
+
+		return 0, errors.Wrap(err, "failed to get members from cabinet")
+	}
+
+	for i, m := range members {
+		if err := s.Cabinet.MemberRemove(guildID, m.User.ID); err != nil {
+			return i, errors.Wrap(err, "failed to remove member")
+		}
+	}
+
+	return len(members), nil
+}
+
+// PrunePresences removes all presences of the guild with the passed id from
+// the Cabinet, and returns the number of removed presences.
+func (s *State) PrunePresences(guildID discord.GuildID) (int, error) {
+	presences, err := s.Cabinet.Presences(guildID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil
+		}
+
+		return 0, errors.Wrap(err, "failed to get presences from cabinet")
+	}
+
+	for i, p := range presences {
+		if err := s.Cabinet.PresenceRemove(guildID, p.User.ID); err != nil {
+			return i, errors.Wrap(err, "failed to remove presence")
+		}
+	}
+
+	return len(presences), nil
+}
+
+// PruneGuild removes the members, presences and messages of the guild with
+// the passed id from the Cabinet.
+// The guild itself, its channels, roles, emojis and voice states are kept,
+// as they are kept up to date by the gateway anyway.
+func (s *State) PruneGuild(guildID discord.GuildID) error {
+	if _, err := s.PruneMembers(guildID); err != nil {
+		return err
+	}
+
+	if _, err := s.PrunePresences(guildID); err != nil {
+		return err
+	}
+
+	channels, err := s.Cabinet.Channels(guildID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+
+		return errors.Wrap(err, "failed to get channels from cabinet")
+	}
+
+	for _, c := range channels {
+		if _, err := s.PruneChannelMessages(c.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cachedChannels returns the guild and private channels stored in the
+// Cabinet.
+func (s *State) cachedChannels() ([]discord.Channel, error) {
+	channels, err := s.Cabinet.PrivateChannels()
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, errors.Wrap(err, "failed to get private channels from cabinet")
+	}
+
+	guilds, err := s.Cabinet.Guilds()
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return channels, nil
+		}
+
+		return nil, errors.Wrap(err, "failed to get guilds from cabinet")
+	}
+
+	for _, g := range guilds {
+		gc, err := s.Cabinet.Channels(g.ID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+
+			return nil, errors.Wrap(err, "failed to get channels from cabinet")
+		}
+
+		channels = append(channels, gc...)
+	}
+
+	return channels, nil
+}
+
+// pruneMessages removes all messages of the channel with the passed id, for
+// which prune returns true.
+func (s *State) pruneMessages(channelID discord.ChannelID, prune func(discord.Message) bool) (int, error) {
+	msgs, err := s.Cabinet.Messages(channelID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil
+		}
+
+		return 0, errors.Wrap(err, "failed to get messages from cabinet")
+	}
+
+	pruned := 0
+
+	for _, m := range msgs {
+		if !prune(m) {
+			continue
+		}
+
+		if err := s.Cabinet.MessageRemove(channelID, m.ID); err != nil {
+			return pruned, errors.Wrap(err, "failed to remove message")
+		}
+
+		pruned++
+	}
+
+	return pruned, nil
+}
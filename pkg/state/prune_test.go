@@ -0,0 +1,74 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
+	"github.com/mavolin/dismock/v2/pkg/dismock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPruneState creates a new State using a defaultstore Cabinet containing
+// guild 1 with channel 2.
+func newPruneState(t *testing.T) *State {
+	t.Helper()
+
+	_, se := dismock.NewSession(t)
+	s := NewFromSession(se, defaultstore.New())
+
+	require.NoError(t, s.Cabinet.GuildSet(discord.Guild{ID: 1}))
+	require.NoError(t, s.Cabinet.ChannelSet(discord.Channel{ID: 2, GuildID: 1}))
+
+	return s
+}
+
+func TestState_PruneMessages(t *testing.T) {
+	s := newPruneState(t)
+
+	oldID := discord.MessageID(discord.NewSnowflake(time.Now().Add(-2 * time.Hour)))
+	newID := discord.MessageID(discord.NewSnowflake(time.Now()))
+
+	require.NoError(t, s.Cabinet.MessageSet(discord.Message{ID: oldID, ChannelID: 2, GuildID: 1}))
+	require.NoError(t, s.Cabinet.MessageSet(discord.Message{ID: newID, ChannelID: 2, GuildID: 1}))
+
+	n, err := s.PruneMessages(time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	msgs, err := s.Cabinet.Messages(2)
+	require.NoError(t, err)
+
+	require.Len(t, msgs, 1)
+	assert.Equal(t, newID, msgs[0].ID)
+}
+
+func TestState_PruneGuild(t *testing.T) {
+	s := newPruneState(t)
+
+	require.NoError(t, s.Cabinet.MemberSet(1, discord.Member{User: discord.User{ID: 3}}))
+	require.NoError(t, s.Cabinet.MemberSet(1, discord.Member{User: discord.User{ID: 4}}))
+	require.NoError(t, s.Cabinet.MessageSet(discord.Message{ID: 5, ChannelID: 2, GuildID: 1}))
+
+	s.chunkedGuilds.Add(1)
+
+	require.NoError(t, s.PruneGuild(1))
+
+	members, _ := s.Cabinet.Members(1)
+	assert.Empty(t, members)
+
+	msgs, _ := s.Cabinet.Messages(2)
+	assert.Empty(t, msgs)
+
+	// the members are no longer complete
+	assert.False(t, s.chunkedGuilds.Contains(1))
+
+	// the guild and its channels are kept
+	_, err := s.Cabinet.Guild(1)
+	assert.NoError(t, err)
+
+	_, err = s.Cabinet.Channel(2)
+	assert.NoError(t, err)
+}
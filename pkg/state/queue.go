@@ -0,0 +1,100 @@
+package state
+
+import "sync/atomic"
+
+// OverflowPolicy is the policy used, if the event queue of an EventHandler is
+// full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock blocks reading events from the gateway, until there is
+	// space in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest event in the queue, to make space
+	// for the new event.
+	OverflowDropOldest
+	// OverflowDropNewest discards the new event.
+	OverflowDropNewest
+)
+
+// Dropped returns the number of events that were dropped, because the event
+// queue was full.
+func (h *EventHandler) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// queueLength returns the number of events in the event queue.
+func (h *EventHandler) queueLength() int {
+	if q, ok := h.queue.Load().(chan interface{}); ok {
+		return len(q)
+	}
+
+	return 0
+}
+
+// queueEvents starts reading from the passed events channel into a queue of
+// size QueueSize, until closer is closed.
+// It returns a channel to read the queued events from.
+//
+// If stop is closed, or the events channel is closed, the queue stops reading
+// events and is closed, so that the events in it can still be read.
+func (h *EventHandler) queueEvents(
+	events <-chan interface{}, stop, closer <-chan struct{},
+) <-chan interface{} {
+	queue := make(chan interface{}, h.QueueSize)
+	h.queue.Store(queue)
+
+	policy := h.OverflowPolicy
+
+	go func() {
+		for {
+			var (
+				e  interface{}
+				ok bool
+			)
+
+			select {
+			case <-closer:
+				return
+			case <-stop:
+				close(queue)
+				return
+			case e, ok = <-events:
+				if !ok {
+					close(queue)
+					return
+				}
+			}
+
+			switch policy {
+			case OverflowDropOldest:
+				for sent := false; !sent; {
+					select {
+					case queue <- e:
+						sent = true
+					default:
+						select {
+						case <-queue:
+							atomic.AddUint64(&h.dropped, 1)
+						default:
+						}
+					}
+				}
+			case OverflowDropNewest:
+				select {
+				case queue <- e:
+				default:
+					atomic.AddUint64(&h.dropped, 1)
+				}
+			default:
+				select {
+				case <-closer:
+					return
+				case queue <- e:
+				}
+			}
+		}
+	}()
+
+	return queue
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventHandler_queueEvents(t *testing.T) {
+	// fill sends the events 1, 2 and 3 to a queue of size 2, that isn't read
+	// from, and returns the events that remain in the queue.
+	fill := func(t *testing.T, policy OverflowPolicy) (queued []interface{}, dropped uint64) {
+		_, s := NewMocker(t)
+		s.QueueSize = 2
+		s.OverflowPolicy = policy
+
+		events := make(chan interface{})
+		stop := make(chan struct{})
+		closer := make(chan struct{})
+
+		defer close(closer)
+
+		queue := s.queueEvents(events, stop, closer)
+
+		for i := 1; i <= 3; i++ {
+			events <- i
+		}
+
+		close(stop)
+
+		for e := range queue {
+			queued = append(queued, e)
+		}
+
+		return queued, s.Dropped()
+	}
+
+	t.Run("drop oldest", func(t *testing.T) {
+		queued, dropped := fill(t, OverflowDropOldest)
+		assert.Equal(t, []interface{}{2, 3}, queued)
+		assert.Equal(t, uint64(1), dropped)
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		queued, dropped := fill(t, OverflowDropNewest)
+		assert.Equal(t, []interface{}{1, 2}, queued)
+		assert.Equal(t, uint64(1), dropped)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		_, s := NewMocker(t)
+		s.QueueSize = 2
+		s.OverflowPolicy = OverflowBlock
+
+		events := make(chan interface{})
+		stop := make(chan struct{})
+		closer := make(chan struct{})
+
+		queue := s.queueEvents(events, stop, closer)
+
+		// the third event is read, but the queue blocks while sending it
+		for i := 1; i <= 3; i++ {
+			events <- i
+		}
+
+		select {
+		case events <- 4:
+			t.Fatal("queue read an event while it was full")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		assert.Equal(t, 1, <-queue)
+
+		select {
+		case events <- 4:
+		case <-time.After(time.Second):
+			t.Fatal("queue didn't read an event after there was space")
+		}
+
+		close(closer)
+
+		assert.Zero(t, s.Dropped())
+	})
+}
@@ -0,0 +1,173 @@
+package state
+
+import (
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+// SetReadThrough sets the resources for which cache misses fall through to
+// the API.
+// By default, all resources are read through, as arikawa does.
+// For all resources not in r, the methods of State will only consult the
+// Cabinet, and return store.ErrNotFound on a miss.
+//
+// Presences and voice states are never fetched from the API, since Discord
+// provides no endpoint for them.
+// Methods of the embedded arikawa State that don't have a counterpart on
+// State, such as Permissions, aren't affected.
+//
+// SetReadThrough must not be called concurrently with any of the affected
+// methods.
+func (s *State) SetReadThrough(r Resource) {
+	s.noReadThrough = AllResources &^ r
+}
+
+// readsThrough checks if cache misses of r should fall through to the API.
+func (s *State) readsThrough(r Resource) bool {
+	return !s.noReadThrough.Has(r)
+}
+
+// Channel returns the channel with the passed id.
+// If the channel is not in the Cabinet, and ResourceChannels is read through,
+// it is fetched from the API.
+func (s *State) Channel(id discord.ChannelID) (*discord.Channel, error) {
+	if !s.readsThrough(ResourceChannels) {
+		return s.Cabinet.Channel(id)
+	}
+
+	return s.State.Channel(id)
+}
+
+// Channels returns the channels of the guild with the passed id.
+// If the channels are not in the Cabinet, and ResourceChannels is read
+// through, they are fetched from the API.
+func (s *State) Channels(guildID discord.GuildID) ([]discord.Channel, error) {
+	if !s.readsThrough(ResourceChannels) {
+		return s.Cabinet.Channels(guildID)
+	}
+
+	return s.State.Channels(guildID)
+}
+
+// PrivateChannels returns the private channels of the user.
+// If the channels are not in the Cabinet, and ResourceChannels is read
+// through, they are fetched from the API.
+func (s *State) PrivateChannels() ([]discord.Channel, error) {
+	if !s.readsThrough(ResourceChannels) {
+		return s.Cabinet.PrivateChannels()
+	}
+
+	return s.State.PrivateChannels()
+}
+
+// Emoji returns the emoji with the passed id from the guild with the passed
+// id.
+// If the emoji is not in the Cabinet, and ResourceEmojis is read through, it
+// is fetched from the API.
+func (s *State) Emoji(guildID discord.GuildID, emojiID discord.EmojiID) (*discord.Emoji, error) {
+	if !s.readsThrough(ResourceEmojis) {
+		return s.Cabinet.Emoji(guildID, emojiID)
+	}
+
+	return s.State.Emoji(guildID, emojiID)
+}
+
+// Emojis returns the emojis of the guild with the passed id.
+// If the emojis are not in the Cabinet, and ResourceEmojis is read through,
+// they are fetched from the API.
+func (s *State) Emojis(guildID discord.GuildID) ([]discord.Emoji, error) {
+	if !s.readsThrough(ResourceEmojis) {
+		return s.Cabinet.Emojis(guildID)
+	}
+
+	return s.State.Emojis(guildID)
+}
+
+// Guild returns the guild with the passed id.
+// If the guild is not in the Cabinet, and ResourceGuilds is read through, it
+// is fetched from the API.
+func (s *State) Guild(id discord.GuildID) (*discord.Guild, error) {
+	if !s.readsThrough(ResourceGuilds) {
+		return s.Cabinet.Guild(id)
+	}
+
+	return s.State.Guild(id)
+}
+
+// Guilds returns the guilds of the user.
+// If the guilds are not in the Cabinet, and ResourceGuilds is read through,
+// they are fetched from the API.
+func (s *State) Guilds() ([]discord.Guild, error) {
+	if !s.readsThrough(ResourceGuilds) {
+		return s.Cabinet.Guilds()
+	}
+
+	return s.State.Guilds()
+}
+
+// Member returns the member with the passed user id from the guild with the
+// passed id.
+// If the member is not in the Cabinet, and ResourceMembers is read through,
+// it is fetched from the API.
+func (s *State) Member(guildID discord.GuildID, userID discord.UserID) (*discord.Member, error) {
+	if !s.readsThrough(ResourceMembers) {
+		return s.Cabinet.Member(guildID, userID)
+	}
+
+	return s.State.Member(guildID, userID)
+}
+
+// Members returns the members of the guild with the passed id.
+// If the members are not in the Cabinet, and ResourceMembers is read
+// through, they are fetched from the API.
+func (s *State) Members(guildID discord.GuildID) ([]discord.Member, error) {
+	if !s.readsThrough(ResourceMembers) {
+		return s.Cabinet.Members(guildID)
+	}
+
+	return s.State.Members(guildID)
+}
+
+// Message returns the message with the passed id from the channel with the
+// passed id.
+// If the message is not in the Cabinet, and ResourceMessages is read
+// through, it is fetched from the API.
+func (s *State) Message(channelID discord.ChannelID, messageID discord.MessageID) (*discord.Message, error) {
+	if !s.readsThrough(ResourceMessages) {
+		return s.Cabinet.Message(channelID, messageID)
+	}
+
+	return s.State.Message(channelID, messageID)
+}
+
+// Messages returns the messages of the channel with the passed id.
+// If the messages are not in the Cabinet, and ResourceMessages is read
+// through, they are fetched from the API.
+func (s *State) Messages(channelID discord.ChannelID) ([]discord.Message, error) {
+	if !s.readsThrough(ResourceMessages) {
+		return s.Cabinet.Messages(channelID)
+	}
+
+	return s.State.Messages(channelID)
+}
+
+// Role returns the role with the passed id from the guild with the passed id.
+// If the role is not in the Cabinet, and ResourceRoles is read through, it is
+// fetched from the API.
+func (s *State) Role(guildID discord.GuildID, roleID discord.RoleID) (*discord.Role, error) {
+	if !s.readsThrough(ResourceRoles) {
+		return s.Cabinet.Role(guildID, roleID)
+	}
+
+	return s.State.Role(guildID, roleID)
+}
+
+// Roles returns the roles of the guild with the passed id.
+// If the roles are not in the Cabinet, and ResourceRoles is read through,
+// they are fetched from the API.
+func (s *State) Roles(guildID discord.GuildID) ([]discord.Role, error) {
+	if !s.readsThrough(ResourceRoles) {
+		return s.Cabinet.Roles(guildID)
+	}
+
+	return s.State.Roles(guildID)
+}
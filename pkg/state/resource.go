@@ -0,0 +1,32 @@
+package state
+
+// Resource is a bitset of resources stored in the Cabinet.
+type Resource uint16
+
+const (
+	// ResourceChannels are guild and private channels.
+	ResourceChannels Resource = 1 << iota
+	// ResourceEmojis are guild emojis.
+	ResourceEmojis
+	// ResourceGuilds are guilds.
+	ResourceGuilds
+	// ResourceMembers are guild members.
+	ResourceMembers
+	// ResourceMessages are messages.
+	ResourceMessages
+	// ResourcePresences are presences.
+	ResourcePresences
+	// ResourceRoles are guild roles.
+	ResourceRoles
+	// ResourceVoiceStates are voice states.
+	ResourceVoiceStates
+
+	// AllResources is a Resource containing all resources.
+	AllResources = ResourceChannels | ResourceEmojis | ResourceGuilds | ResourceMembers |
+		ResourceMessages | ResourcePresences | ResourceRoles | ResourceVoiceStates
+)
+
+// Has checks if r contains all resources in other.
+func (r Resource) Has(other Resource) bool {
+	return r&other == other
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/diamondburned/arikawa/v2/utils/httputil/httpdriver"
+)
+
+// ErrCustomDriver gets returned by SetTransport, if the API client doesn't use
+// the default httpdriver, and the transport can therefore not be replaced.
+var ErrCustomDriver = errors.New("state: the api client uses a custom httpdriver")
+
+// SetTransport replaces the http.RoundTripper used by the API client, while
+// keeping all other settings of the client, such as the timeout, untouched.
+//
+// If the API client doesn't use the default httpdriver, ErrCustomDriver will
+// be returned.
+//
+// SetTransport must not be called concurrently with API calls.
+func (s *State) SetTransport(t http.RoundTripper) error {
+	return setTransport(&s.Client.Client.Client, t)
+}
+
+func setTransport(driver *httpdriver.Client, t http.RoundTripper) error {
+	switch d := (*driver).(type) {
+	case httpdriver.DefaultClient:
+		d.Transport = t
+		*driver = d
+	case *httpdriver.DefaultClient:
+		cp := *d
+		cp.Transport = t
+		*driver = &cp
+	case *retryDriver:
+		return setTransport(&d.Client, t)
+	default:
+		return ErrCustomDriver
+	}
+
+	return nil
+}
+
+// AddRequestHook adds a hook that is called before every request made by the
+// API client, after the default headers, such as the Authorization header,
+// were added.
+//
+// If the hook returns an error, the request will fail with that error.
+//
+// AddRequestHook must not be called concurrently with API calls.
+func (s *State) AddRequestHook(f httputil.RequestOption) {
+	s.Client.Client.OnRequest = append(s.Client.Client.OnRequest, f)
+}
+
+// AddResponseHook adds a hook that is called after every request made by the
+// API client.
+// The response may be nil, if the request failed.
+//
+// If the hook returns an error, it will override the error returned by the
+// request.
+//
+// AddResponseHook must not be called concurrently with API calls.
+func (s *State) AddResponseHook(f httputil.ResponseFunc) {
+	s.Client.Client.OnResponse = append(s.Client.Client.OnResponse, f)
+}
+
+// SetUserAgentSuffix appends the passed suffix to the User-Agent header of all
+// requests made by the API client, separated by a space.
+//
+// This has no effect, if the API client doesn't use the default httpdriver.
+func (s *State) SetUserAgentSuffix(suffix string) {
+	s.AddRequestHook(func(r httpdriver.Request) error {
+		if dr, ok := r.(*httpdriver.DefaultRequest); ok {
+			dr.Header.Set("User-Agent", dr.Header.Get("User-Agent")+" "+suffix)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,255 @@
+package state
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api/rate"
+	"github.com/diamondburned/arikawa/v2/utils/httputil"
+	"github.com/diamondburned/arikawa/v2/utils/httputil/httpdriver"
+)
+
+// RetryPolicy is the policy used to retry failed idempotent API requests.
+//
+// A request is considered idempotent, if it is a GET or HEAD request, or if
+// it is a PUT or DELETE request whose body can be resent.
+// Requests are retried, if they fail with a network error, or a 5xx status
+// code other than 501.
+//
+// 429 responses are not retried by the RetryPolicy, but by the API client
+// itself, which waits for the rate limit to reset before retrying.
+type RetryPolicy struct {
+	// MaxRetries is the maximum amount of times a single request is retried.
+	MaxRetries int
+	// BaseDelay is the maximum delay before the first retry.
+	// The maximum delay is doubled with every retry, until it reaches
+	// MaxDelay.
+	// The actual delay is randomly chosen between 0 and the maximum delay.
+	BaseDelay time.Duration
+	// MaxDelay is the upper bound for the delay between two retries.
+	MaxDelay time.Duration
+	// Budget is the maximum amount of retries that may be made within a
+	// minute, across all requests.
+	// If the budget is exhausted, requests will fail immediately.
+	//
+	// If Budget is 0, the budget is unlimited.
+	Budget int
+
+	// OnRetry, if not nil, gets called before a request is retried.
+	// attempt is the number of the upcoming retry, starting at 1, and wait
+	// the time until the request is retried.
+	// resp is nil, if the request failed with err.
+	OnRetry func(r httpdriver.Request, resp httpdriver.Response, err error, attempt int, wait time.Duration)
+}
+
+// SetRetryPolicy wraps the httpdriver of the API client, so that failed
+// idempotent requests are retried using the passed RetryPolicy.
+//
+// Every retry acquires the rate limiter of the API client, and the failed
+// responses are passed to the response hooks of the API client, before the
+// request is retried.
+// Errors returned by the response hooks for failed responses are ignored.
+//
+// Only requests made through the API client are retried.
+// Requests made by using the httpdriver of the API client directly are
+// passed through unchanged, as no rate limiter was acquired for them.
+//
+// The Retries of the API client are left untouched, and still apply to all
+// requests, including non-idempotent ones.
+// Once the RetryPolicy gives up on an idempotent request, the API client may
+// therefore retry it again, if it failed with a network error or a 5xx.
+//
+// SetRetryPolicy must not be called concurrently with API calls.
+func (s *State) SetRetryPolicy(p RetryPolicy) {
+	if rd, ok := s.Client.Client.Client.(*retryDriver); ok {
+		rd.policy = p
+		return
+	}
+
+	rd := &retryDriver{
+		Client:  s.Client.Client.Client,
+		policy:  p,
+		client:  s.Client.Client,
+		limiter: s.Client.Limiter,
+	}
+
+	s.Client.Client.Client = rd
+	s.Client.Client.OnRequest = append(s.Client.Client.OnRequest, rd.track)
+	s.Client.Client.OnResponse = append(s.Client.Client.OnResponse, rd.untrack)
+}
+
+type retryDriver struct {
+	httpdriver.Client
+	policy RetryPolicy
+
+	// client is the API client, whose response hooks are called with the
+	// responses of failed attempts.
+	client *httputil.Client
+	// limiter is the rate limiter acquired before every retry.
+	limiter *rate.Limiter
+
+	// tracked are the requests that are currently made through the API
+	// client.
+	// Only those are retried, as only for those the rate limiter was
+	// acquired, and the response hooks will be called.
+	tracked sync.Map
+
+	budgetMutex sync.Mutex
+	budgetUsed  int
+	budgetReset time.Time
+}
+
+// track marks the passed request as made through the API client.
+// It is used as request hook of the API client.
+func (d *retryDriver) track(r httpdriver.Request) error {
+	d.tracked.Store(r, struct{}{})
+	return nil
+}
+
+// untrack removes the mark of track from the passed request.
+// It is used as response hook of the API client.
+func (d *retryDriver) untrack(r httpdriver.Request, _ httpdriver.Response) error {
+	d.tracked.Delete(r)
+	return nil
+}
+
+func (d *retryDriver) Do(r httpdriver.Request) (httpdriver.Response, error) {
+	if _, ok := d.tracked.Load(r); !ok || !isIdempotent(r) {
+		return d.Client.Do(r)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := d.Client.Do(r)
+		if attempt > d.policy.MaxRetries {
+			return resp, err
+		}
+
+		wait, ok := d.retryDelay(resp, err, attempt)
+		if !ok || !d.useBudget() {
+			return resp, err
+		}
+
+		// The API client only calls its response hooks with the response we
+		// return, so we need to call them for the responses we discard, in
+		// order to release the rate limiter.
+		for _, f := range d.client.OnResponse {
+			_ = f(r, resp)
+		}
+
+		if resp != nil {
+			resp.GetBody().Close()
+		}
+
+		if d.policy.OnRetry != nil {
+			d.policy.OnRetry(r, resp, err, attempt, wait)
+		}
+
+		t := time.NewTimer(wait)
+
+		select {
+		case <-r.GetContext().Done():
+			t.Stop()
+			return nil, r.GetContext().Err()
+		case <-t.C:
+		}
+
+		if err := resetBody(r); err != nil {
+			return nil, err
+		}
+
+		if d.limiter != nil {
+			if err := d.limiter.Acquire(r.GetContext(), r.GetPath()); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// retryDelay returns the time to wait, before retrying the request that
+// resulted in the passed response and error.
+// If the request shouldn't be retried, ok will be false.
+func (d *retryDriver) retryDelay(
+	resp httpdriver.Response, err error, attempt int,
+) (wait time.Duration, ok bool) {
+	if err == nil {
+		if status := resp.GetStatus(); status < 500 || status == http.StatusNotImplemented {
+			return 0, false
+		}
+	}
+
+	return backoff(d.policy.BaseDelay, d.policy.MaxDelay, attempt), true
+}
+
+// backoff returns a random delay between 0 and base doubled attempt-1 times,
+// capped at max, if max is greater than 0.
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	upper := base << uint(attempt-1)
+	// upper < base means we overflowed
+	if max > 0 && (upper > max || upper < base) {
+		upper = max
+	}
+
+	if upper <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(upper)))
+}
+
+// useBudget attempts to use one retry of the budget of the retryDriver, and
+// reports whether that succeeded.
+func (d *retryDriver) useBudget() bool {
+	if d.policy.Budget <= 0 {
+		return true
+	}
+
+	d.budgetMutex.Lock()
+	defer d.budgetMutex.Unlock()
+
+	if now := time.Now(); now.After(d.budgetReset) {
+		d.budgetUsed = 0
+		d.budgetReset = now.Add(time.Minute)
+	}
+
+	if d.budgetUsed >= d.policy.Budget {
+		return false
+	}
+
+	d.budgetUsed++
+	return true
+}
+
+// isIdempotent checks if the passed request is idempotent and can be resent.
+func isIdempotent(r httpdriver.Request) bool {
+	dr, ok := r.(*httpdriver.DefaultRequest)
+	if !ok {
+		return false
+	}
+
+	switch dr.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPut, http.MethodDelete:
+		return dr.Body == nil || dr.Body == http.NoBody || dr.GetBody != nil
+	default:
+		return false
+	}
+}
+
+// resetBody prepares the body of the passed request to be resent.
+func resetBody(r httpdriver.Request) error {
+	dr, ok := r.(*httpdriver.DefaultRequest)
+	if !ok || dr.GetBody == nil {
+		return nil
+	}
+
+	body, err := dr.GetBody()
+	if err != nil {
+		return err
+	}
+
+	dr.Body = body
+	return nil
+}
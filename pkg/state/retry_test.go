@@ -0,0 +1,142 @@
+package state
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/session"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/diamondburned/arikawa/v2/utils/httputil/httpdriver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRetryState creates a new State using an API client with the default
+// rate limiter and hooks, and a server that responds with the passed status
+// codes in order.
+// Once all status codes were used, the server responds with 200.
+func newRetryState(t *testing.T, statuses ...int) (s *State, srv *httptest.Server, requests *int32) {
+	t.Helper()
+
+	requests = new(int32)
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(requests, 1)) - 1
+		if i < len(statuses) {
+			w.WriteHeader(statuses[i])
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	s = NewFromSession(session.NewWithGateway(gateway.NewCustomGateway("", "Bot abc")), store.NoopCabinet)
+	s.Client.Client.Retries = 1
+
+	return s, srv, requests
+}
+
+func TestState_SetRetryPolicy(t *testing.T) {
+	t.Run("idempotent", func(t *testing.T) {
+		s, srv, requests := newRetryState(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+
+		var hookCalls int32
+
+		s.Client.Client.OnResponse = append(s.Client.Client.OnResponse,
+			func(httpdriver.Request, httpdriver.Response) error {
+				atomic.AddInt32(&hookCalls, 1)
+				return nil
+			})
+
+		var retries []int
+
+		s.SetRetryPolicy(RetryPolicy{
+			MaxRetries: 3,
+			OnRetry: func(_ httpdriver.Request, _ httpdriver.Response, _ error, attempt int, _ time.Duration) {
+				retries = append(retries, attempt)
+			},
+		})
+
+		resp, err := s.Client.Client.Request(http.MethodGet, srv.URL+"/api/v8/channels/1")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.GetStatus())
+
+		assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+		assert.Equal(t, []int{1, 2}, retries)
+		// every response, including the discarded ones, must be passed to the
+		// hooks, so that the rate limiter is released
+		assert.Equal(t, int32(3), atomic.LoadInt32(&hookCalls))
+
+		// if the rate limiter weren't released, this would block
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_, err = s.Client.Client.WithContext(ctx).Request(http.MethodGet, srv.URL+"/api/v8/channels/1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("non-idempotent", func(t *testing.T) {
+		s, srv, requests := newRetryState(t, http.StatusServiceUnavailable)
+		s.SetRetryPolicy(RetryPolicy{MaxRetries: 3})
+
+		_, err := s.Client.Client.Request(http.MethodPost, srv.URL+"/api/v8/channels/1/messages")
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			status int
+		}{
+			{name: "not implemented", status: http.StatusNotImplemented},
+			{name: "not found", status: http.StatusNotFound},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				s, srv, requests := newRetryState(t, c.status)
+				s.SetRetryPolicy(RetryPolicy{MaxRetries: 3})
+
+				_, err := s.Client.Client.Request(http.MethodGet, srv.URL+"/api/v8/channels/1")
+				assert.Error(t, err)
+				assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+			})
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		s, srv, requests := newRetryState(t,
+			http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+		s.SetRetryPolicy(RetryPolicy{MaxRetries: 3, Budget: 1})
+
+		_, err := s.Client.Client.Request(http.MethodGet, srv.URL+"/api/v8/channels/1")
+		assert.Error(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	})
+
+	t.Run("direct driver use", func(t *testing.T) {
+		s, srv, requests := newRetryState(t, http.StatusServiceUnavailable)
+		s.SetRetryPolicy(RetryPolicy{MaxRetries: 3})
+
+		driver := s.Client.Client.Client
+
+		req, err := driver.NewRequest(context.Background(), http.MethodGet, srv.URL+"/api/v8/channels/1")
+		require.NoError(t, err)
+
+		resp, err := driver.Do(req)
+		require.NoError(t, err)
+		resp.GetBody().Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.GetStatus())
+		assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ErrShutdownTimeout gets returned by Run, if the State didn't shut down
+// within the shutdown timeout.
+var ErrShutdownTimeout = errors.New("state: timed out waiting for the state to shut down")
+
+// Run opens the passed State and blocks until the passed context is canceled,
+// or the process receives a SIGINT or SIGTERM.
+// Then, it closes the State, waiting for all handlers to finish executing and
+// all Modules to close.
+// If the context is canceled or a signal is received while the State is
+// still opening, opening is aborted, and the error is returned.
+//
+// If the shutdown takes longer than shutdownTimeout, Run returns
+// ErrShutdownTimeout, without waiting for the shutdown to complete.
+// If shutdownTimeout is 0, Run waits indefinitely.
+func Run(ctx context.Context, s *State, shutdownTimeout time.Duration) error {
+	// Register before opening, so that signals received while opening don't
+	// terminate the process.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-sig:
+			cancel()
+		}
+	}()
+
+	if err := s.OpenCtx(ctx); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+
+	closeCtx := context.Background()
+
+	if shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+
+		closeCtx, cancel = context.WithTimeout(closeCtx, shutdownTimeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.closeContext(closeCtx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-closeCtx.Done():
+		return ErrShutdownTimeout
+	}
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("signal while opening", func(t *testing.T) {
+		s := newUnreachableState(t)
+		s.Gateway.WSTimeout = 20 * time.Millisecond
+
+		var once sync.Once
+
+		s.SetOpenRetryPolicy(OpenRetryPolicy{
+			MaxRetries: 1000,
+			BaseDelay:  10 * time.Millisecond,
+			// Run registered its signal handler, once opening is retried.
+			OnRetry: func(error, int, time.Duration) {
+				once.Do(func() {
+					p, err := os.FindProcess(os.Getpid())
+					if assert.NoError(t, err) {
+						assert.NoError(t, p.Signal(syscall.SIGTERM))
+					}
+				})
+			},
+		})
+
+		done := make(chan error, 1)
+		go func() { done <- Run(context.Background(), s, time.Second) }()
+
+		select {
+		case err := <-done:
+			assert.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run didn't abort opening after receiving a signal")
+		}
+	})
+
+	t.Run("context canceled while opening", func(t *testing.T) {
+		s := newUnreachableState(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := Run(ctx, s, time.Second)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"context"
+	"net"
+	"net/url"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/state/store"
+	"github.com/pkg/errors"
+)
+
+// SelfTestReport is the report created by SelfTest.
+type SelfTestReport struct {
+	// User is the user the token belongs to.
+	// It is nil, if the token is invalid.
+	User *discord.User
+	// TokenErr is the error that occurred while validating the token.
+	TokenErr error
+
+	// GatewayURL is the URL of the gateway.
+	GatewayURL string
+	// SessionStartLimit is the session start limit of the bot.
+	// It is nil, if it couldn't be retrieved.
+	SessionStartLimit *gateway.SessionStartLimit
+	// GatewayErr is the error that occurred while checking if the gateway is
+	// reachable.
+	GatewayErr error
+
+	// MissingIntents are the intents that are required by the added handlers
+	// and middlewares, but weren't added to the gateway.
+	MissingIntents gateway.Intents
+
+	// CabinetErr is the error that occurred while reading from and writing to
+	// the Cabinet.
+	CabinetErr error
+}
+
+// OK checks if all tests of the SelfTestReport passed.
+func (r *SelfTestReport) OK() bool {
+	return r.TokenErr == nil && r.GatewayErr == nil && r.MissingIntents == 0 && r.CabinetErr == nil
+}
+
+// SelfTest checks if the State is ready to be opened.
+// It verifies that the token is valid, that the gateway is reachable, that all
+// intents required by the added handlers were added, and that the Cabinet can
+// be read from and written to.
+//
+// SelfTest should be called before the State is opened, after all handlers
+// were added.
+// All tests are run, even if one of them fails.
+func (s *State) SelfTest(ctx context.Context) *SelfTestReport {
+	var r SelfTestReport
+
+	cs := s.WithContext(ctx)
+
+	r.User, r.TokenErr = cs.Client.Me()
+	if r.TokenErr != nil {
+		r.TokenErr = errors.Wrap(r.TokenErr, "failed to validate token")
+	}
+
+	r.GatewayURL, r.SessionStartLimit, r.GatewayErr = cs.selfTestGateway(ctx)
+
+	if s.Gateway.Identifier.Intents != 0 {
+		r.MissingIntents = s.DeriveIntents() &^ s.Gateway.Identifier.Intents
+	}
+
+	r.CabinetErr = s.selfTestCabinet(r.User)
+
+	return &r
+}
+
+// selfTestGateway retrieves the gateway URL and checks if the gateway can be
+// connected to.
+func (s *State) selfTestGateway(ctx context.Context) (string, *gateway.SessionStartLimit, error) {
+	var data gateway.BotData
+
+	if err := s.Client.Client.RequestJSON(&data, "GET", gateway.EndpointGatewayBot); err != nil {
+		return "", nil, errors.Wrap(err, "failed to get gateway url")
+	}
+
+	u, err := url.Parse(data.URL)
+	if err != nil {
+		return data.URL, data.StartLimit, errors.Wrap(err, "failed to parse gateway url")
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return data.URL, data.StartLimit, errors.Wrap(err, "failed to connect to gateway")
+	}
+
+	return data.URL, data.StartLimit, conn.Close()
+}
+
+// selfTestCabinet checks if the Cabinet is healthy.
+// If u is not nil, it is written to the Cabinet and read back.
+// Otherwise, the Cabinet is only read from.
+func (s *State) selfTestCabinet(u *discord.User) error {
+	if u == nil {
+		if _, err := s.Cabinet.Me(); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return errors.Wrap(err, "failed to read from cabinet")
+		}
+
+		return nil
+	}
+
+	if err := s.Cabinet.MyselfSet(*u); err != nil {
+		return errors.Wrap(err, "failed to write to cabinet")
+	}
+
+	me, err := s.Cabinet.Me()
+	if err != nil {
+		return errors.Wrap(err, "failed to read from cabinet")
+	}
+
+	if me.ID != u.ID {
+		return errors.New("cabinet returned a different user than was written")
+	}
+
+	return nil
+}
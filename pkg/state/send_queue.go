@@ -0,0 +1,277 @@
+package state
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+// ErrSendQueueClosed gets returned by SendQueue.Enqueue, if the SendQueue was
+// closed.
+var ErrSendQueueClosed = errors.New("state: the send queue is closed")
+
+// maxContentLength is the maximum length of a message's content.
+const maxContentLength = 2000
+
+// SendQueue is a per-channel queue for sending messages, that respects the
+// slowmode of channels.
+//
+// Instead of failing, messages are delayed until the slowmode of the channel
+// allows sending the next message.
+// Rate limits are handled by the API client.
+//
+// Errors that occur when sending are passed to the EventHandler's
+// ErrorHandlerFull, or its ErrorHandler, if ErrorHandlerFull is nil.
+type SendQueue struct {
+	s        *State
+	coalesce bool
+
+	channels map[discord.ChannelID]*channelQueue
+	mutex    sync.Mutex
+
+	done   chan struct{}
+	closed bool
+}
+
+type channelQueue struct {
+	pending []api.SendMessageData
+	// lastSent is the time the last message was sent.
+	// Once the queue is empty, it is kept until the slowmode of the channel
+	// expired.
+	lastSent time.Time
+	// running specifies whether a worker is sending the messages of the
+	// queue.
+	running bool
+}
+
+// NewSendQueue creates a new SendQueue.
+//
+// If coalesce is true, consecutive queued messages that only consist of
+// content will be joined using newlines and sent as a single message, as long
+// as the combined content doesn't exceed 2000 characters.
+func (s *State) NewSendQueue(coalesce bool) *SendQueue {
+	return &SendQueue{
+		s:        s,
+		coalesce: coalesce,
+		channels: make(map[discord.ChannelID]*channelQueue),
+		done:     make(chan struct{}),
+	}
+}
+
+// Enqueue queues the passed message to be sent in the channel with the passed
+// id.
+func (q *SendQueue) Enqueue(channelID discord.ChannelID, data api.SendMessageData) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrSendQueueClosed
+	}
+
+	cq, ok := q.channels[channelID]
+	if !ok {
+		cq = new(channelQueue)
+		q.channels[channelID] = cq
+	}
+
+	cq.pending = append(cq.pending, data)
+
+	if !cq.running {
+		cq.running = true
+		go q.work(channelID, cq)
+	}
+
+	return nil
+}
+
+// Pending returns the number of messages queued for the channel with the
+// passed id.
+func (q *SendQueue) Pending(channelID discord.ChannelID) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if cq, ok := q.channels[channelID]; ok {
+		return len(cq.pending)
+	}
+
+	return 0
+}
+
+// Channels returns the ids of all channels that have queued messages.
+func (q *SendQueue) Channels() []discord.ChannelID {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	ids := make([]discord.ChannelID, 0, len(q.channels))
+
+	for id, cq := range q.channels {
+		if len(cq.pending) > 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// Clear discards all messages queued for the channel with the passed id, and
+// returns the number of discarded messages.
+func (q *SendQueue) Clear(channelID discord.ChannelID) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	cq, ok := q.channels[channelID]
+	if !ok {
+		return 0
+	}
+
+	n := len(cq.pending)
+	cq.pending = nil
+
+	return n
+}
+
+// Close discards all queued messages and stops the SendQueue.
+// Messages that are currently being sent won't be canceled.
+func (q *SendQueue) Close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	q.closed = true
+	close(q.done)
+
+	for _, cq := range q.channels {
+		cq.pending = nil
+	}
+}
+
+// work sends the queued messages of the passed channelQueue, until the queue
+// is empty.
+func (q *SendQueue) work(channelID discord.ChannelID, cq *channelQueue) {
+	for {
+		q.mutex.Lock()
+		lastSent := cq.lastSent
+		empty := len(cq.pending) == 0
+		q.mutex.Unlock()
+
+		wait := time.Until(lastSent.Add(q.slowmode(channelID)))
+
+		if empty {
+			if q.stop(channelID, cq, wait) {
+				return
+			}
+
+			continue
+		}
+
+		if wait > 0 {
+			t := time.NewTimer(wait)
+
+			select {
+			case <-q.done:
+				t.Stop()
+			case <-t.C:
+			}
+		}
+
+		q.mutex.Lock()
+
+		if len(cq.pending) == 0 {
+			q.mutex.Unlock()
+			continue
+		}
+
+		data := q.next(cq)
+
+		q.mutex.Unlock()
+
+		_, err := q.s.SendMessageComplex(channelID, data)
+
+		q.mutex.Lock()
+		cq.lastSent = time.Now()
+		q.mutex.Unlock()
+
+		if err != nil {
+			q.s.handleError(err, nil, "SendQueue")
+		}
+	}
+}
+
+// stop stops the worker of the passed channelQueue, if no messages were
+// queued in the meantime, and reports whether it did so.
+//
+// If the slowmode of the channel expires only after wait, the channelQueue is
+// kept until then, so that the next message still respects the slowmode.
+func (q *SendQueue) stop(channelID discord.ChannelID, cq *channelQueue, wait time.Duration) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(cq.pending) > 0 {
+		return false
+	}
+
+	cq.running = false
+
+	if wait <= 0 {
+		delete(q.channels, channelID)
+		return true
+	}
+
+	time.AfterFunc(wait, func() {
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+
+		if !cq.running && q.channels[channelID] == cq {
+			delete(q.channels, channelID)
+		}
+	})
+
+	return true
+}
+
+// next pops the next message to send from the passed channelQueue.
+// If coalescing is enabled, consecutive messages are joined, if possible.
+func (q *SendQueue) next(cq *channelQueue) api.SendMessageData {
+	data := cq.pending[0]
+	cq.pending = cq.pending[1:]
+
+	if !q.coalesce || !contentOnly(data) {
+		return data
+	}
+
+	for len(cq.pending) > 0 {
+		n := cq.pending[0]
+		if !contentOnly(n) || len(data.Content)+1+len(n.Content) > maxContentLength {
+			break
+		}
+
+		data.Content += "\n" + n.Content
+		cq.pending = cq.pending[1:]
+	}
+
+	return data
+}
+
+// slowmode returns the slowmode of the channel with the passed id.
+// If the channel can't be retrieved, 0 is returned.
+func (q *SendQueue) slowmode(channelID discord.ChannelID) time.Duration {
+	c, err := q.s.Channel(channelID)
+	if err != nil {
+		return 0
+	}
+
+	return c.UserRateLimit.Duration()
+}
+
+// contentOnly checks if the passed api.SendMessageData only consists of
+// content, and can therefore be coalesced.
+func contentOnly(data api.SendMessageData) bool {
+	return data.Content != "" && data.Embed == nil && len(data.Files) == 0 &&
+		!data.TTS && data.Reference == nil && data.AllowedMentions == nil && data.Nonce == ""
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
+	"github.com/mavolin/dismock/v2/pkg/dismock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendQueue_Enqueue(t *testing.T) {
+	t.Run("send", func(t *testing.T) {
+		m, se := dismock.NewSession(t)
+
+		s := NewFromSession(se, defaultstore.New())
+		require.NoError(t, s.Cabinet.ChannelSet(discord.Channel{ID: 1, GuildID: 2}))
+
+		data := api.SendMessageData{Content: "abc"}
+		m.SendMessageComplex(data, discord.Message{ID: 2, ChannelID: 1, Content: data.Content})
+
+		q := s.NewSendQueue(false)
+		defer q.Close()
+
+		require.NoError(t, q.Enqueue(1, data))
+
+		// the channel is removed, once the message was sent
+		assert.Eventually(t, func() bool {
+			q.mutex.Lock()
+			defer q.mutex.Unlock()
+
+			return len(q.channels) == 0
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		q := s.NewSendQueue(false)
+		q.Close()
+
+		err := q.Enqueue(1, api.SendMessageData{Content: "abc"})
+		assert.Equal(t, ErrSendQueueClosed, err)
+	})
+}
+
+func TestSendQueue_next(t *testing.T) {
+	testCases := []struct {
+		name     string
+		coalesce bool
+		pending  []api.SendMessageData
+		expect   api.SendMessageData
+		remain   int
+	}{
+		{
+			name:     "no coalescing",
+			coalesce: false,
+			pending:  []api.SendMessageData{{Content: "abc"}, {Content: "def"}},
+			expect:   api.SendMessageData{Content: "abc"},
+			remain:   1,
+		},
+		{
+			name:     "coalesce",
+			coalesce: true,
+			pending:  []api.SendMessageData{{Content: "abc"}, {Content: "def"}, {Content: "ghi"}},
+			expect:   api.SendMessageData{Content: "abc\ndef\nghi"},
+			remain:   0,
+		},
+		{
+			name:     "not content only",
+			coalesce: true,
+			pending: []api.SendMessageData{
+				{Content: "abc"}, {Content: "def", TTS: true}, {Content: "ghi"},
+			},
+			expect: api.SendMessageData{Content: "abc"},
+			remain: 2,
+		},
+		{
+			name:     "too long",
+			coalesce: true,
+			pending: []api.SendMessageData{
+				{Content: strings.Repeat("a", maxContentLength-3)}, {Content: "abc"},
+			},
+			expect: api.SendMessageData{Content: strings.Repeat("a", maxContentLength-3)},
+			remain: 1,
+		},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			_, s := NewMocker(t)
+
+			q := s.NewSendQueue(c.coalesce)
+			defer q.Close()
+
+			cq := &channelQueue{pending: c.pending}
+
+			actual := q.next(cq)
+			assert.Equal(t, c.expect, actual)
+			assert.Len(t, cq.pending, c.remain)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// ErrUnknownShard gets returned by RestartShard, if the State doesn't run
+// the shard with the passed id.
+var ErrUnknownShard = errors.New("state: the state does not run a shard with the passed id")
+
+// ShardState is the connection state of a shard.
+type ShardState uint8
+
+const (
+	// ShardClosed is the state of a shard that is not connected.
+	ShardClosed ShardState = iota
+	// ShardConnecting is the state of a shard that is connecting, but didn't
+	// receive a Ready event yet.
+	ShardConnecting
+	// ShardResuming is the state of a shard that received a Ready event,
+	// but whose last heartbeat wasn't acknowledged within twice the
+	// heartrate, because it is reconnecting.
+	ShardResuming
+	// ShardReady is the state of a connected shard.
+	ShardReady
+)
+
+func (s ShardState) String() string {
+	switch s {
+	case ShardClosed:
+		return "closed"
+	case ShardConnecting:
+		return "connecting"
+	case ShardResuming:
+		return "resuming"
+	case ShardReady:
+		return "ready"
+	default:
+		return "unknown"
+	}
+}
+
+// ShardStatus is the status of a single shard.
+type ShardStatus struct {
+	// ID is the id of the shard.
+	ID int
+	// Count is the total number of shards.
+	Count int
+
+	// State is the connection state of the shard.
+	State ShardState
+	// Latency is the time between the last heartbeat and its
+	// acknowledgement.
+	// It is 0, if the last heartbeat wasn't acknowledged yet.
+	Latency time.Duration
+	// SessionID is the id of the gateway session.
+	SessionID string
+	// Sequence is the sequence number of the last received event.
+	Sequence int64
+	// Guilds is the number of guilds stored in the Cabinet.
+	Guilds int
+}
+
+// ShardInfo returns the status of the shards of the State.
+//
+// Since the State manages a single gateway connection, the returned slice
+// always contains exactly one ShardStatus.
+// If no shard was set in the Identifier of the gateway, ID and Count will
+// be 0 and 1 respectively.
+func (s *State) ShardInfo() []ShardStatus {
+	status := ShardStatus{
+		Count:     1,
+		State:     s.shardState(),
+		Latency:   s.latency(),
+		SessionID: s.Gateway.SessionID(),
+		Sequence:  s.Gateway.Sequence.Get(),
+	}
+
+	if shard := s.Gateway.Identifier.Shard; shard != nil {
+		status.ID = shard.ShardID()
+		status.Count = shard.NumShards()
+	}
+
+	if guilds, err := s.Cabinet.Guilds(); err == nil {
+		status.Guilds = len(guilds)
+	}
+
+	return []ShardStatus{status}
+}
+
+// shardState returns the ShardState of the gateway.
+func (s *State) shardState() ShardState {
+	switch {
+	case atomic.LoadUint32(&s.open) == 0:
+		return ShardClosed
+	case atomic.LoadUint32(&s.ready) == 0:
+		return ShardConnecting
+	}
+
+	ack := s.Gateway.PacerLoop.EchoBeat.Get()
+	if ack == 0 || time.Since(time.Unix(0, ack)) > 2*s.Gateway.PacerLoop.Heartrate.Get() {
+		return ShardResuming
+	}
+
+	return ShardReady
+}
+
+// Latency returns the time between the last heartbeat of the shard with the
+// passed id and its acknowledgement, i.e. the round-trip time to the gateway.
+//
+// It returns 0, if the last heartbeat wasn't acknowledged yet, or if the
+// State doesn't run the shard with the passed id.
+func (s *State) Latency(shardID int) time.Duration {
+	if shardID != s.shardID() {
+		return 0
+	}
+
+	return s.latency()
+}
+
+// AverageLatency returns the average Latency of all shards of the State.
+// Since the State manages a single gateway connection, this is the latency
+// of that connection.
+func (s *State) AverageLatency() time.Duration {
+	return s.latency()
+}
+
+// latency returns the time between the last heartbeat and its
+// acknowledgement, or 0, if it wasn't acknowledged yet.
+func (s *State) latency() time.Duration {
+	sent := s.Gateway.PacerLoop.SentBeat.Get()
+	echo := s.Gateway.PacerLoop.EchoBeat.Get()
+
+	if sent == 0 || echo < sent {
+		return 0
+	}
+
+	return time.Duration(echo - sent)
+}
+
+// RestartShard closes the gateway connection of the shard with the passed
+// id and reconnects it, resuming the session, if possible.
+// Event handling is not interrupted, and the Cabinet is kept.
+//
+// Failed attempts to reconnect are retried according to the OpenRetryPolicy
+// of the State.
+//
+// RestartShard blocks until the shard is reconnected, reconnecting failed,
+// or the passed context is canceled.
+// If the context is canceled before the shard is reconnected, the context's
+// error is returned, and the shard stays disconnected.
+func (s *State) RestartShard(ctx context.Context, shardID int) error {
+	if id := s.shardID(); shardID != id {
+		return ErrUnknownShard
+	}
+
+	// Don't use Gateway.ReconnectCtx, as it calls the FatalErrorCallback of
+	// the gateway, if the context expires, which is nil by default.
+	_ = s.Gateway.Close()
+
+	if err := s.openGateway(ctx); err != nil {
+		// Dialing may fail because of the deadline of the context, before the
+		// context itself reports that it expired.
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			return context.DeadlineExceeded
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// RollingRestart restarts all shards of the State one at a time, using
+// RestartShard.
+// Since the State manages a single gateway connection, this restarts that
+// connection.
+func (s *State) RollingRestart(ctx context.Context) error {
+	return s.RestartShard(ctx, s.shardID())
+}
+
+// UpdateStatusOnShard updates the status of the shard with the passed id.
+//
+// If the State doesn't run the shard with the passed id, ErrUnknownShard
+// will be returned.
+func (s *State) UpdateStatusOnShard(ctx context.Context, shardID int, data gateway.UpdateStatusData) error {
+	if shardID != s.shardID() {
+		return ErrUnknownShard
+	}
+
+	return s.Gateway.UpdateStatusCtx(ctx, data)
+}
+
+// UpdateGuildVoiceState updates the voice state of the bot in the guild with
+// the passed id, using the shard the guild belongs to.
+// The GuildID of data is set to guildID.
+//
+// If the State doesn't run the shard the guild belongs to, ErrUnknownShard
+// will be returned.
+func (s *State) UpdateGuildVoiceState(
+	ctx context.Context, guildID discord.GuildID, data gateway.UpdateVoiceStateData,
+) error {
+	if s.guildShard(guildID) != s.shardID() {
+		return ErrUnknownShard
+	}
+
+	data.GuildID = guildID
+	return s.Gateway.UpdateVoiceStateCtx(ctx, data)
+}
+
+// guildShard returns the id of the shard the guild with the passed id
+// belongs to.
+func (s *State) guildShard(guildID discord.GuildID) int {
+	shard := s.Gateway.Identifier.Shard
+	if shard == nil || shard.NumShards() <= 1 {
+		return 0
+	}
+
+	return int((uint64(guildID) >> 22) % uint64(shard.NumShards()))
+}
+
+// shardID returns the id of the shard run by the State.
+func (s *State) shardID() int {
+	if shard := s.Gateway.Identifier.Shard; shard != nil {
+		return shard.ShardID()
+	}
+
+	return 0
+}
+
+// SetShardPresence sets the function used to create the presence the shards
+// of the State identify with, e.g. to show "shard 3/64".
+// f is called with the id of the shard and the total number of shards every
+// time the State is opened.
+//
+// SetShardPresence must be called before the State is opened.
+func (s *State) SetShardPresence(f func(shardID, shardCount int) gateway.UpdateStatusData) {
+	s.shardPresence = f
+}
+
+// applyShardPresence sets the presence of the Identifier of the gateway, if a
+// shard presence func was set.
+func (s *State) applyShardPresence() {
+	if s.shardPresence == nil {
+		return
+	}
+
+	count := 1
+	if shard := s.Gateway.Identifier.Shard; shard != nil {
+		count = shard.NumShards()
+	}
+
+	p := s.shardPresence(s.shardID(), count)
+	s.Gateway.Identifier.Presence = &p
+}
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// shardRestartTimeout is the maximum duration a restart of a shard started by
+// MonitorShards may take.
+const shardRestartTimeout = time.Minute
+
+// MonitorShards starts a background check of the heartbeats of the shards of
+// the State, that runs every interval.
+// If the last heartbeat of a shard wasn't acknowledged within
+// maxHeartbeatAge, the shard is considered dead, and is restarted using
+// RestartShard.
+// Once it is reconnected, a ShardRecoveredEvent is dispatched.
+// Errors that occur while restarting are passed to the ErrorHandlerFull, or
+// the ErrorHandler, if ErrorHandlerFull is nil.
+//
+// If maxHeartbeatAge is 0, twice the heartrate of the gateway is used.
+//
+// Shards are only checked, while the State is open, and after they received
+// a Ready event.
+// The returned function stops the monitor.
+// A restart that is already in progress isn't aborted, as that would leave
+// the gateway disconnected, and the returned function blocks until it
+// finished, or until shardRestartTimeout passed.
+func (s *State) MonitorShards(interval, maxHeartbeatAge time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				s.checkShard(maxHeartbeatAge, shardRestartTimeout)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// checkShard checks if the heartbeats of the gateway stalled, and if so,
+// restarts it, waiting at most restartTimeout for the restart to complete.
+func (s *State) checkShard(maxHeartbeatAge, restartTimeout time.Duration) {
+	if atomic.LoadUint32(&s.open) == 0 || atomic.LoadUint32(&s.ready) == 0 {
+		return
+	}
+
+	if maxHeartbeatAge <= 0 {
+		maxHeartbeatAge = 2 * s.Gateway.PacerLoop.Heartrate.Get()
+	}
+
+	var lastAck time.Time
+	if ack := s.Gateway.PacerLoop.EchoBeat.Get(); ack != 0 {
+		lastAck = time.Unix(0, ack)
+	}
+
+	if time.Since(lastAck) <= maxHeartbeatAge {
+		return
+	}
+
+	shardID := s.shardID()
+
+	// Don't use the context of the monitor, as canceling the reconnect would
+	// leave the gateway disconnected.
+	ctx, cancel := context.WithTimeout(context.Background(), restartTimeout)
+	defer cancel()
+
+	if err := s.RestartShard(ctx, shardID); err != nil {
+		s.handleError(errors.Wrapf(err, "failed to restart shard %d", shardID), nil, "MonitorShards")
+		return
+	}
+
+	s.dispatch(&ShardRecoveredEvent{
+		Base:             NewBase(),
+		ShardID:          shardID,
+		LastHeartbeatAck: lastAck,
+	})
+}
@@ -0,0 +1,42 @@
+package state
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_checkShard(t *testing.T) {
+	t.Run("healthy", func(t *testing.T) {
+		s := newUnreachableState(t)
+
+		atomic.StoreUint32(&s.open, 1)
+		atomic.StoreUint32(&s.ready, 1)
+		s.Gateway.PacerLoop.EchoBeat.Set(time.Now())
+
+		s.ErrorHandler = func(err error) {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		s.checkShard(time.Minute, 50*time.Millisecond)
+	})
+
+	t.Run("restart timeout", func(t *testing.T) {
+		s := newUnreachableState(t)
+
+		atomic.StoreUint32(&s.open, 1)
+		atomic.StoreUint32(&s.ready, 1)
+
+		var reported error
+
+		s.ErrorHandler = func(err error) { reported = err }
+
+		s.checkShard(time.Second, 50*time.Millisecond)
+
+		assert.Equal(t, context.DeadlineExceeded, errors.Cause(reported))
+	})
+}
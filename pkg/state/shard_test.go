@@ -0,0 +1,60 @@
+package state
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/session"
+	"github.com/diamondburned/arikawa/v2/state/store/defaultstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUnreachableState creates a new State, whose gateway accepts TCP
+// connections, but never completes the websocket handshake.
+func newUnreachableState(t *testing.T) *State {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	gw := gateway.NewCustomGateway("ws://"+l.Addr().String(), "Bot abc")
+	gw.WSTimeout = 0
+
+	return NewFromSession(session.NewWithGateway(gw), defaultstore.New())
+}
+
+func TestState_RestartShard(t *testing.T) {
+	t.Run("unknown shard", func(t *testing.T) {
+		s := newUnreachableState(t)
+
+		err := s.RestartShard(context.Background(), 1)
+		assert.Equal(t, ErrUnknownShard, err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		s := newUnreachableState(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := s.RestartShard(ctx, 0)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
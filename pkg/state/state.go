@@ -32,6 +32,41 @@ type State struct {
 	// unavailable when connecting to the gateway, i.e. they had Unavailable
 	// set to true during Ready.
 	unreadyGuilds *moreatomic.GuildIDSet
+	// chunkedGuilds is a set of discord.GuildIDs of guilds, whose members
+	// are completely stored in the Cabinet.
+	chunkedGuilds *moreatomic.GuildIDSet
+
+	// bans is the cache for the bans of guilds.
+	bans *banStore
+	// typing is the typingAggregator used to generate UserTypingEvents and
+	// UserStoppedTypingEvents.
+	// It is nil, if typing aggregation is disabled.
+	typing *typingAggregator
+	// guildCache is the guildCacheWorker used to store the members and
+	// presences of large guilds in the background.
+	// It is nil, if deferred caching is disabled.
+	guildCache *guildCacheWorker
+
+	// features are the cached feature flags.
+	// It is nil, if no FeatureFlagProvider was set.
+	features *featureFlags
+
+	// modules are the Modules used by the State.
+	modules *moduleList
+
+	// openRetry is the policy used to retry failed attempts to open the
+	// gateway.
+	// It is nil, if opening shouldn't be retried.
+	openRetry *OpenRetryPolicy
+	// shardPresence is the func used to create the presence of the shards.
+	// It is nil, if the presence of the Identifier should be used.
+	shardPresence func(shardID, shardCount int) gateway.UpdateStatusData
+
+	// noReadThrough are the resources for which cache misses don't fall
+	// through to the API.
+	noReadThrough Resource
+	// noCaching are the resources that aren't stored in the Cabinet.
+	noCaching Resource
 }
 
 // New creates a new State using the passed token.
@@ -72,6 +107,9 @@ func NewFromSession(s *session.Session, cabinet store.Cabinet) (st *State) {
 		fewMutex:          new(sync.Mutex),
 		unavailableGuilds: moreatomic.NewGuildIDSet(),
 		unreadyGuilds:     moreatomic.NewGuildIDSet(),
+		chunkedGuilds:     moreatomic.NewGuildIDSet(),
+		bans:              newBanStore(),
+		modules:           new(moduleList),
 	}
 
 	st.EventHandler = NewEventHandler(st)
@@ -88,6 +126,9 @@ func NewFromState(s *state.State) (st *State) {
 		fewMutex:          new(sync.Mutex),
 		unavailableGuilds: moreatomic.NewGuildIDSet(),
 		unreadyGuilds:     moreatomic.NewGuildIDSet(),
+		chunkedGuilds:     moreatomic.NewGuildIDSet(),
+		bans:              newBanStore(),
+		modules:           new(moduleList),
 	}
 
 	st.EventHandler = NewEventHandler(st)
@@ -100,16 +141,28 @@ func NewFromState(s *state.State) (st *State) {
 // method is thread-safe.
 func (s *State) WithContext(ctx context.Context) *State {
 	copied := *s
-	copied.Client = copied.Client.WithContext(ctx)
+	copied.State = s.State.WithContext(ctx)
 
 	return &copied
 }
 
 // Open opens a connection to the gateway.
+// If an OpenRetryPolicy was set, failed attempts are retried.
 func (s *State) Open() error {
+	return s.OpenCtx(context.Background())
+}
+
+// OpenCtx opens a connection to the gateway, aborting if the passed context
+// is canceled before the connection was established.
+// Each attempt is additionally limited by the WSTimeout of the gateway.
+//
+// If an OpenRetryPolicy was set, failed attempts are retried.
+func (s *State) OpenCtx(ctx context.Context) error {
+	s.applyShardPresence()
 	s.EventHandler.Open(s.Gateway.Events)
 
-	if err := s.Gateway.Open(); err != nil {
+	if err := s.openGateway(ctx); err != nil {
+		s.EventHandler.Close()
 		return errors.Wrap(err, "failed to start gateway")
 	}
 
@@ -117,12 +170,30 @@ func (s *State) Open() error {
 }
 
 // Close closes the connection to the gateway and stops listening for events.
-func (s *State) Close() (err error) {
+// Once all handlers have finished executing, the Modules used by the State
+// are closed.
+func (s *State) Close() error {
+	return s.closeContext(context.Background())
+}
+
+// closeContext closes the State, using the passed context to close the
+// Modules.
+func (s *State) closeContext(ctx context.Context) (err error) {
 	err = s.Gateway.Close()
 
+	// discard pending jobs, so that we don't have to wait for them
+	if s.guildCache != nil {
+		s.guildCache.reset()
+	}
+
 	s.EventHandler.Close()
 
 	s.Call(&CloseEvent{Base: NewBase()})
+
+	if merr := s.closeModules(ctx); err == nil {
+		err = merr
+	}
+
 	return
 }
 
@@ -0,0 +1,211 @@
+package state
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+// ErrStickyFiles gets returned by StickyManager.Set, if the passed
+// api.SendMessageData contains files, which can't be resent.
+var ErrStickyFiles = errors.New("state: sticky messages must not contain files")
+
+// StickyManager keeps sticky messages at the bottom of channels.
+//
+// Every time a message is sent in a channel with a sticky message, the sticky
+// message is deleted and sent again.
+// To prevent excessive API calls, the first message after a repost schedules
+// the next repost after the debounce duration, and messages sent in the
+// meantime don't delay it any further.
+// That way, a burst of messages only causes a single repost, while steady
+// traffic still causes a repost at least once per debounce duration.
+//
+// Errors that occur when reposting are passed to the EventHandler's
+// ErrorHandlerFull, or its ErrorHandler, if ErrorHandlerFull is nil.
+type StickyManager struct {
+	s        *State
+	debounce time.Duration
+	rm       func()
+
+	stickies map[discord.ChannelID]*sticky
+	mutex    sync.Mutex
+}
+
+type sticky struct {
+	data api.SendMessageData
+
+	// messageID is the id of the current sticky message.
+	messageID discord.MessageID
+	// reposting specifies whether a repost is in progress.
+	// While reposting, messages of the current user are ignored, as the
+	// repost may be received before its id is known.
+	reposting bool
+	// timer is the timer of the scheduled repost, or nil, if no repost is
+	// scheduled.
+	timer *time.Timer
+}
+
+// NewStickyManager creates a new StickyManager that reposts sticky messages
+// at most once per passed debounce duration.
+// It adds a MessageCreateEvent handler to the State, that is removed when
+// calling Close.
+func (s *State) NewStickyManager(debounce time.Duration) *StickyManager {
+	m := &StickyManager{
+		s:        s,
+		debounce: debounce,
+		stickies: make(map[discord.ChannelID]*sticky),
+	}
+
+	m.rm = s.MustAddHandler(m.onMessageCreate)
+
+	return m
+}
+
+// Set sends the passed message in the channel with the passed id and keeps it
+// at the bottom of the channel.
+// If the channel already has a sticky message, it will be replaced.
+//
+// data must not contain files.
+func (m *StickyManager) Set(channelID discord.ChannelID, data api.SendMessageData) error {
+	if len(data.Files) > 0 {
+		return ErrStickyFiles
+	}
+
+	msg, err := m.s.SendMessageComplex(channelID, data)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+
+	old, ok := m.stickies[channelID]
+	if ok && old.timer != nil {
+		old.timer.Stop()
+	}
+
+	m.stickies[channelID] = &sticky{data: data, messageID: msg.ID}
+
+	m.mutex.Unlock()
+
+	if ok {
+		return m.s.DeleteMessage(channelID, old.messageID)
+	}
+
+	return nil
+}
+
+// Remove removes and deletes the sticky message of the channel with the passed
+// id.
+// If the channel has no sticky message, Remove is a no-op.
+func (m *StickyManager) Remove(channelID discord.ChannelID) error {
+	m.mutex.Lock()
+
+	st, ok := m.stickies[channelID]
+	if ok && st.timer != nil {
+		st.timer.Stop()
+	}
+
+	delete(m.stickies, channelID)
+
+	m.mutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return m.s.DeleteMessage(channelID, st.messageID)
+}
+
+// Close stops the StickyManager.
+// Sticky messages won't be deleted, but will no longer be reposted.
+func (m *StickyManager) Close() {
+	m.rm()
+
+	m.mutex.Lock()
+
+	for _, st := range m.stickies {
+		if st.timer != nil {
+			st.timer.Stop()
+		}
+	}
+
+	m.stickies = make(map[discord.ChannelID]*sticky)
+
+	m.mutex.Unlock()
+}
+
+func (m *StickyManager) onMessageCreate(s *State, e *MessageCreateEvent) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	st, ok := m.stickies[e.ChannelID]
+	if !ok || st.messageID == e.ID {
+		return
+	}
+
+	if st.reposting {
+		if me, err := s.Me(); err == nil && me.ID == e.Author.ID {
+			return
+		}
+	}
+
+	// a repost is already scheduled
+	if st.timer != nil {
+		return
+	}
+
+	m.schedule(e.ChannelID, st)
+}
+
+// schedule schedules a repost of the passed sticky message after the debounce
+// duration.
+// The mutex of the StickyManager must be locked.
+func (m *StickyManager) schedule(channelID discord.ChannelID, st *sticky) {
+	st.timer = time.AfterFunc(m.debounce, func() { m.repost(channelID, st) })
+}
+
+// repost deletes and resends the passed sticky message.
+func (m *StickyManager) repost(channelID discord.ChannelID, st *sticky) {
+	m.mutex.Lock()
+
+	if m.stickies[channelID] != st {
+		m.mutex.Unlock()
+		return
+	}
+
+	// the previous repost is still in progress, try again later
+	if st.reposting {
+		m.schedule(channelID, st)
+		m.mutex.Unlock()
+		return
+	}
+
+	st.timer = nil
+	st.reposting = true
+	oldID := st.messageID
+
+	m.mutex.Unlock()
+
+	msg, err := m.s.SendMessageComplex(channelID, st.data)
+
+	m.mutex.Lock()
+
+	st.reposting = false
+	if err == nil {
+		st.messageID = msg.ID
+	}
+
+	m.mutex.Unlock()
+
+	if err != nil {
+		m.s.handleError(err, nil, "StickyManager")
+		return
+	}
+
+	if err := m.s.DeleteMessage(channelID, oldID); err != nil {
+		m.s.handleError(err, nil, "StickyManager")
+	}
+}
@@ -0,0 +1,86 @@
+package state
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/api"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/utils/sendpart"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStickyManager(t *testing.T) {
+	t.Run("files", func(t *testing.T) {
+		_, s := NewMocker(t)
+
+		sm := s.NewStickyManager(time.Second)
+		defer sm.Close()
+
+		err := sm.Set(1, api.SendMessageData{Files: []sendpart.File{{Name: "abc"}}})
+		assert.Equal(t, ErrStickyFiles, err)
+	})
+
+	t.Run("repost", func(t *testing.T) {
+		m, s := NewMocker(t)
+
+		data := api.SendMessageData{Content: "abc"}
+
+		m.SendMessageComplex(data, discord.Message{ID: 10, ChannelID: 1, Content: data.Content})
+
+		sm := s.NewStickyManager(20 * time.Millisecond)
+		defer sm.Close()
+
+		require.NoError(t, sm.Set(1, data))
+
+		// only the first message schedules a repost, so that both messages
+		// cause a single repost
+		m.SendMessageComplex(data, discord.Message{ID: 11, ChannelID: 1, Content: data.Content})
+
+		deleted := make(chan struct{})
+
+		m.MockAPI("DeleteMessage", http.MethodDelete, "/channels/1/messages/10",
+			func(http.ResponseWriter, *http.Request, *testing.T) { close(deleted) })
+
+		for _, id := range []discord.MessageID{20, 21} {
+			e := newMessageCreateEvent()
+			e.ID = id
+			e.Author.ID = 2
+
+			s.Call(e)
+		}
+
+		s.wg.Wait()
+
+		select {
+		case <-deleted:
+		case <-time.After(time.Second):
+			t.Fatal("sticky message wasn't reposted")
+		}
+
+		sm.mutex.Lock()
+		defer sm.mutex.Unlock()
+
+		assert.Equal(t, discord.MessageID(11), sm.stickies[1].messageID)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		m, s := NewMocker(t)
+
+		data := api.SendMessageData{Content: "abc"}
+		m.SendMessageComplex(data, discord.Message{ID: 10, ChannelID: 1, Content: data.Content})
+
+		sm := s.NewStickyManager(10 * time.Millisecond)
+		require.NoError(t, sm.Set(1, data))
+
+		sm.Close()
+
+		s.Call(newMessageCreateEvent())
+		s.wg.Wait()
+
+		// a repost would fail the test, as it isn't mocked
+		time.Sleep(30 * time.Millisecond)
+	})
+}
@@ -0,0 +1,191 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// DefaultTypingTimeout is the recommended timeout for
+// State.EnableTypingAggregation.
+// Discord sends a TypingStartEvent roughly every 8 seconds, as long as the user
+// keeps typing.
+const DefaultTypingTimeout = 10 * time.Second
+
+// EnableTypingAggregation enables the dispatching of UserTypingEvents and
+// UserStoppedTypingEvents.
+//
+// A user is considered to have stopped typing, if they send a message or if
+// they don't send a TypingStartEvent for the duration of the passed timeout.
+//
+// EnableTypingAggregation must be called before the State is opened.
+func (s *State) EnableTypingAggregation(timeout time.Duration) {
+	s.typing = &typingAggregator{
+		h:        s.EventHandler,
+		timeout:  timeout,
+		sessions: make(map[typingKey]*typingSession),
+	}
+}
+
+type (
+	// typingAggregator aggregates TypingStartEvents into UserTypingEvents and
+	// UserStoppedTypingEvents.
+	typingAggregator struct {
+		h       *EventHandler
+		timeout time.Duration
+
+		sessions map[typingKey]*typingSession
+		// closed specifies whether the EventHandler was closed.
+		// While closed, no events are dispatched, as the WaitGroup of the
+		// EventHandler may already be waited on.
+		closed bool
+		mutex  sync.Mutex
+	}
+
+	typingKey struct {
+		channelID discord.ChannelID
+		userID    discord.UserID
+	}
+
+	typingSession struct {
+		guildID discord.GuildID
+		timer   *time.Timer
+		// gen is incremented every time the timer is replaced, so that timers
+		// that fired, before they could be stopped, can be ignored.
+		gen uint64
+	}
+)
+
+// onEvent updates the typingAggregator using the passed gateway event.
+func (a *typingAggregator) onEvent(e interface{}) {
+	switch e := e.(type) {
+	case *gateway.TypingStartEvent:
+		a.onTypingStart(e)
+	case *gateway.MessageCreateEvent:
+		a.onMessageCreate(e)
+	}
+}
+
+func (a *typingAggregator) onTypingStart(e *gateway.TypingStartEvent) {
+	k := typingKey{channelID: e.ChannelID, userID: e.UserID}
+
+	a.mutex.Lock()
+
+	if a.closed {
+		a.mutex.Unlock()
+		return
+	}
+
+	if sess, ok := a.sessions[k]; ok {
+		sess.timer.Stop()
+		sess.gen++
+		sess.timer = a.startTimer(k, sess)
+
+		a.mutex.Unlock()
+		return
+	}
+
+	sess := &typingSession{guildID: e.GuildID}
+	sess.timer = a.startTimer(k, sess)
+	a.sessions[k] = sess
+
+	a.mutex.Unlock()
+
+	a.h.dispatch(&UserTypingEvent{
+		TypingStartEvent: e,
+		Base:             NewBase(),
+	})
+}
+
+// startTimer starts the timer that ends the passed typingSession.
+// The typingAggregator must be locked.
+func (a *typingAggregator) startTimer(k typingKey, sess *typingSession) *time.Timer {
+	gen := sess.gen
+
+	return time.AfterFunc(a.timeout, func() {
+		a.mutex.Lock()
+
+		if a.closed || a.sessions[k] != sess || sess.gen != gen {
+			a.mutex.Unlock()
+			return
+		}
+
+		delete(a.sessions, k)
+
+		// Add the event to the WaitGroup of the EventHandler while locked, so
+		// that close can't return before, but dispatch it after unlocking, so
+		// that the event loop doesn't have to wait for the dispatch.
+		a.h.wg.Add(1)
+
+		a.mutex.Unlock()
+
+		a.h.dispatchAdded(&UserStoppedTypingEvent{
+			Base:      NewBase(),
+			ChannelID: k.channelID,
+			GuildID:   sess.guildID,
+			UserID:    k.userID,
+		})
+	})
+}
+
+func (a *typingAggregator) onMessageCreate(e *gateway.MessageCreateEvent) {
+	k := typingKey{channelID: e.ChannelID, userID: e.Author.ID}
+
+	a.mutex.Lock()
+
+	sess, ok := a.sessions[k]
+	if !ok {
+		a.mutex.Unlock()
+		return
+	}
+
+	sess.timer.Stop()
+	delete(a.sessions, k)
+
+	a.mutex.Unlock()
+
+	// copy, so that handlers of the UserStoppedTypingEvent don't share the
+	// message with those of the MessageCreateEvent
+	msg := e.Message
+
+	a.h.dispatch(&UserStoppedTypingEvent{
+		Base:      NewBase(),
+		ChannelID: k.channelID,
+		GuildID:   sess.guildID,
+		UserID:    k.userID,
+		Message:   &msg,
+	})
+}
+
+// close stops all typing sessions without dispatching
+// UserStoppedTypingEvents, and ignores all events until reset is called.
+func (a *typingAggregator) close() {
+	a.mutex.Lock()
+	a.closed = true
+	a.mutex.Unlock()
+
+	a.reset()
+}
+
+// reset stops all typing sessions without dispatching
+// UserStoppedTypingEvents.
+func (a *typingAggregator) reset() {
+	a.mutex.Lock()
+
+	for _, sess := range a.sessions {
+		sess.timer.Stop()
+	}
+
+	a.sessions = make(map[typingKey]*typingSession)
+
+	a.mutex.Unlock()
+}
+
+// open makes the typingAggregator handle events again, after it was closed.
+func (a *typingAggregator) open() {
+	a.mutex.Lock()
+	a.closed = false
+	a.mutex.Unlock()
+}
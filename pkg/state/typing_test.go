@@ -0,0 +1,104 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState_EnableTypingAggregation(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		_, s := NewMocker(t)
+		s.EnableTypingAggregation(10 * time.Millisecond)
+
+		typing := make(chan *UserTypingEvent, 1)
+		stopped := make(chan *UserStoppedTypingEvent, 1)
+
+		s.MustAddHandler(func(_ *State, e *UserTypingEvent) { typing <- e })
+		s.MustAddHandler(func(_ *State, e *UserStoppedTypingEvent) { stopped <- e })
+
+		events := make(chan interface{}, 1)
+		s.EventHandler.Open(events)
+
+		defer s.EventHandler.Close()
+
+		events <- &gateway.TypingStartEvent{ChannelID: 1, GuildID: 2, UserID: 3}
+
+		select {
+		case e := <-typing:
+			assert.Equal(t, discord.UserID(3), e.UserID)
+		case <-time.After(time.Second):
+			t.Fatal("no UserTypingEvent was dispatched")
+		}
+
+		select {
+		case e := <-stopped:
+			assert.Equal(t, discord.ChannelID(1), e.ChannelID)
+			assert.Equal(t, discord.GuildID(2), e.GuildID)
+			assert.Equal(t, discord.UserID(3), e.UserID)
+			assert.Nil(t, e.Message)
+		case <-time.After(time.Second):
+			t.Fatal("no UserStoppedTypingEvent was dispatched")
+		}
+	})
+
+	t.Run("message", func(t *testing.T) {
+		_, s := NewMocker(t)
+		s.EnableTypingAggregation(time.Hour)
+
+		stopped := make(chan *UserStoppedTypingEvent, 1)
+		s.MustAddHandler(func(_ *State, e *UserStoppedTypingEvent) { stopped <- e })
+
+		events := make(chan interface{}, 2)
+		s.EventHandler.Open(events)
+
+		defer s.EventHandler.Close()
+
+		events <- &gateway.TypingStartEvent{ChannelID: 1, UserID: 3}
+		events <- &gateway.MessageCreateEvent{
+			Message: discord.Message{ID: 4, ChannelID: 1, Author: discord.User{ID: 3}},
+		}
+
+		select {
+		case e := <-stopped:
+			require.NotNil(t, e.Message)
+			assert.Equal(t, discord.MessageID(4), e.Message.ID)
+		case <-time.After(time.Second):
+			t.Fatal("no UserStoppedTypingEvent was dispatched")
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		_, s := NewMocker(t)
+		s.EnableTypingAggregation(20 * time.Millisecond)
+
+		typing := make(chan struct{}, 1)
+		stopped := make(chan struct{}, 1)
+
+		s.MustAddHandler(func(*State, *UserTypingEvent) { typing <- struct{}{} })
+		s.MustAddHandler(func(*State, *UserStoppedTypingEvent) { stopped <- struct{}{} })
+
+		events := make(chan interface{}, 1)
+		s.EventHandler.Open(events)
+
+		events <- &gateway.TypingStartEvent{ChannelID: 1, UserID: 3}
+
+		select {
+		case <-typing:
+		case <-time.After(time.Second):
+			t.Fatal("no UserTypingEvent was dispatched")
+		}
+
+		s.EventHandler.Close()
+
+		select {
+		case <-stopped:
+			t.Fatal("UserStoppedTypingEvent was dispatched after closing")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
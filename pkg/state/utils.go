@@ -1,32 +1,122 @@
 package state
 
 import (
+	"errors"
 	"reflect"
+	"runtime"
+	"time"
 
 	"github.com/diamondburned/arikawa/v2/discord"
 	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/diamondburned/arikawa/v2/state/store"
 )
 
-// handleResult handles the passed result of a handler func.
-func (h *EventHandler) handleResult(res []reflect.Value) bool {
-	if len(res) == 0 {
-		return false
-	}
+// handleResult handles the passed result of the handler or middleware func
+// fn, that was called using the event ev.
+func (h *EventHandler) handleResult(res []reflect.Value, ev, fn reflect.Value) bool {
+	err := resultError(res)
+	if errors.Is(err, Filtered) {
+		if h.OnFiltered != nil {
+			var reason string
+
+			var ferr *FilterError
+			if errors.As(err, &ferr) {
+				reason = ferr.Reason
+			}
+
+			h.OnFiltered(ev.Interface(), funcName(fn), reason)
+		}
 
-	err := res[0].Interface()
-	if err == Filtered {
 		return true
 	} else if err != nil {
-		h.ErrorHandler(err.(error))
+		h.handleError(err, ev.Interface(), funcName(fn))
 		return true
 	}
 
 	return false
 }
 
+// handleError passes the passed error to the ErrorHandlerFull, or the
+// ErrorHandler, if ErrorHandlerFull is nil.
+func (h *EventHandler) handleError(err error, e interface{}, handlerName string) {
+	if h.ErrorHandlerFull != nil {
+		h.ErrorHandlerFull(err, e, handlerName)
+	} else {
+		h.ErrorHandler(err)
+	}
+}
+
+// funcName returns the name of the func stored in the passed reflect.Value.
+func funcName(fn reflect.Value) string {
+	if f := runtime.FuncForPC(fn.Pointer()); f != nil {
+		return f.Name()
+	}
+
+	return ""
+}
+
+// resultError extracts the error from the passed result of a handler or
+// middleware func.
+func resultError(res []reflect.Value) error {
+	if len(res) == 0 {
+		return nil
+	}
+
+	err, _ := res[0].Interface().(error)
+	return err
+}
+
+// reportLag reports the lag of the passed event to the LagHandler, if the
+// event carries a timestamp and was received from the gateway.
+func (h *EventHandler) reportLag(e interface{}) {
+	if h.LagHandler == nil {
+		return
+	}
+
+	var (
+		ts   time.Time
+		base *Base
+	)
+
+	switch e := e.(type) {
+	case *MessageCreateEvent:
+		ts, base = e.Timestamp.Time(), e.Base
+	case *MessageUpdateEvent:
+		if !e.EditedTimestamp.IsValid() {
+			return
+		}
+
+		ts, base = e.EditedTimestamp.Time(), e.Base
+	case *TypingStartEvent:
+		ts, base = e.Timestamp.Time(), e.Base
+	default:
+		return
+	}
+
+	if base.receivedAt.IsZero() {
+		return
+	}
+
+	h.LagHandler(e, base.receivedAt.Sub(ts), time.Since(base.receivedAt))
+}
+
+// storeError reports the passed error, that was returned by a Cabinet lookup
+// made while generating the event for src, to the StoreErrorHandler.
+// nil errors and store.ErrNotFound are ignored.
+func (h *EventHandler) storeError(err error, src interface{}) {
+	if err == nil || h.StoreErrorHandler == nil || errors.Is(err, store.ErrNotFound) {
+		return
+	}
+
+	h.StoreErrorHandler(err, src)
+}
+
 // genEvent generates a disstate event from the passed arikawa event.
 func (h *EventHandler) genEvent(src interface{}) interface{} {
 	base := NewBase()
+	base.receivedAt = time.Now()
+
+	base.shardID = h.s.shardID()
 
 	switch src := src.(type) {
 	// ---------------- Ready Event ----------------
@@ -43,7 +133,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Base:               base,
 		}
 	case *gateway.ChannelUpdateEvent:
-		c, _ := h.s.Cabinet.Channel(src.ID)
+		c, err := h.s.Cabinet.Channel(src.ID)
+		h.storeError(err, src)
 
 		return &ChannelUpdateEvent{
 			ChannelUpdateEvent: src,
@@ -51,7 +142,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Old:                c,
 		}
 	case *gateway.ChannelDeleteEvent:
-		c, _ := h.s.Cabinet.Channel(src.ID)
+		c, err := h.s.Cabinet.Channel(src.ID)
+		h.storeError(err, src)
 
 		return &ChannelDeleteEvent{
 			ChannelDeleteEvent: src,
@@ -76,7 +168,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Base:             base,
 		}
 	case *gateway.GuildUpdateEvent:
-		g, _ := h.s.Cabinet.Guild(src.ID)
+		g, err := h.s.Cabinet.Guild(src.ID)
+		h.storeError(err, src)
 
 		return &GuildUpdateEvent{
 			GuildUpdateEvent: src,
@@ -84,7 +177,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Old:              g,
 		}
 	case *gateway.GuildDeleteEvent:
-		g, _ := h.s.Cabinet.Guild(src.ID)
+		g, err := h.s.Cabinet.Guild(src.ID)
+		h.storeError(err, src)
 
 		return &GuildDeleteEvent{
 			GuildDeleteEvent: src,
@@ -97,12 +191,19 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Base:             base,
 		}
 	case *gateway.GuildBanRemoveEvent:
-		return &GuildBanRemoveEvent{
+		e := &GuildBanRemoveEvent{
 			GuildBanRemoveEvent: src,
 			Base:                base,
 		}
+
+		if b, ok, _ := h.s.bans.ban(src.GuildID, src.User.ID); ok {
+			e.Old = &b
+		}
+
+		return e
 	case *gateway.GuildEmojisUpdateEvent:
-		e, _ := h.s.Cabinet.Emojis(src.GuildID)
+		e, err := h.s.Cabinet.Emojis(src.GuildID)
+		h.storeError(err, src)
 
 		return &GuildEmojisUpdateEvent{
 			GuildEmojisUpdateEvent: src,
@@ -120,7 +221,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Base:                base,
 		}
 	case *gateway.GuildMemberRemoveEvent:
-		m, _ := h.s.Cabinet.Member(src.GuildID, src.User.ID)
+		m, err := h.s.Cabinet.Member(src.GuildID, src.User.ID)
+		h.storeError(err, src)
 
 		return &GuildMemberRemoveEvent{
 			GuildMemberRemoveEvent: src,
@@ -128,7 +230,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Old:                    m,
 		}
 	case *gateway.GuildMemberUpdateEvent:
-		m, _ := h.s.Cabinet.Member(src.GuildID, src.User.ID)
+		m, err := h.s.Cabinet.Member(src.GuildID, src.User.ID)
+		h.storeError(err, src)
 
 		return &GuildMemberUpdateEvent{
 			GuildMemberUpdateEvent: src,
@@ -146,7 +249,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Base:                 base,
 		}
 	case *gateway.GuildRoleUpdateEvent:
-		r, _ := h.s.Cabinet.Role(src.GuildID, src.Role.ID)
+		r, err := h.s.Cabinet.Role(src.GuildID, src.Role.ID)
+		h.storeError(err, src)
 
 		return &GuildRoleUpdateEvent{
 			GuildRoleUpdateEvent: src,
@@ -154,7 +258,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Old:                  r,
 		}
 	case *gateway.GuildRoleDeleteEvent:
-		r, _ := h.s.Cabinet.Role(src.GuildID, src.RoleID)
+		r, err := h.s.Cabinet.Role(src.GuildID, src.RoleID)
+		h.storeError(err, src)
 
 		return &GuildRoleDeleteEvent{
 			GuildRoleDeleteEvent: src,
@@ -181,7 +286,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Base:               base,
 		}
 	case *gateway.MessageUpdateEvent:
-		m, _ := h.s.Cabinet.Message(src.ChannelID, src.ID)
+		m, err := h.s.Cabinet.Message(src.ChannelID, src.ID)
+		h.storeError(err, src)
 
 		return &MessageUpdateEvent{
 			MessageUpdateEvent: src,
@@ -189,7 +295,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			Old:                m,
 		}
 	case *gateway.MessageDeleteEvent:
-		m, _ := h.s.Cabinet.Message(src.ChannelID, src.ID)
+		m, err := h.s.Cabinet.Message(src.ChannelID, src.ID)
+		h.storeError(err, src)
 
 		return &MessageDeleteEvent{
 			MessageDeleteEvent: src,
@@ -203,6 +310,8 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 			m, err := h.s.Cabinet.Message(src.ChannelID, id)
 			if err == nil {
 				msgs = append(msgs, *m)
+			} else {
+				h.storeError(err, src)
 			}
 		}
 
@@ -239,7 +348,13 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 
 	// ---------------- Presence Events ----------------
 	case *gateway.PresenceUpdateEvent:
-		p, _ := h.s.Cabinet.Presence(src.GuildID, src.User.ID)
+		var p *gateway.Presence
+		if !h.s.noCaching.Has(ResourcePresences) {
+			var err error
+
+			p, err = h.s.Cabinet.Presence(src.GuildID, src.User.ID)
+			h.storeError(err, src)
+		}
 
 		return &PresenceUpdateEvent{
 			PresenceUpdateEvent: src,
@@ -298,9 +413,13 @@ func (h *EventHandler) genEvent(src interface{}) interface{} {
 
 	// ---------------- Voice Events ----------------
 	case *gateway.VoiceStateUpdateEvent:
+		vs, err := h.s.Cabinet.VoiceState(src.GuildID, src.UserID)
+		h.storeError(err, src)
+
 		return &VoiceStateUpdateEvent{
 			VoiceStateUpdateEvent: src,
 			Base:                  base,
+			Old:                   vs,
 		}
 	case *gateway.VoiceServerUpdateEvent:
 		return &VoiceServerUpdateEvent{
@@ -331,12 +450,68 @@ func copyEvent(v reflect.Value, t reflect.Type) reflect.Value {
 		cp.Field(i).Set(v.Field(i))
 	}
 
-	b := v.FieldByName("Base").Interface().(*Base)
-	bcp := b.copy()
+	if f, ok := t.Elem().FieldByName("Base"); ok && len(f.Index) == 1 {
+		b := v.Field(f.Index[0]).Interface().(*Base)
+		bcp := b.copy()
 
-	bcpValue := reflect.ValueOf(bcp)
+		bcpValue := reflect.ValueOf(bcp)
 
-	cp.FieldByName("Base").Set(bcpValue)
+		cp.Field(f.Index[0]).Set(bcpValue)
+	} else {
+		// Situation-specific events, such as GuildReadyEvent, embed the event
+		// they are derived from, which holds the Base.
+		// Copy it as well, so that we don't modify the Base of the original.
+		parent := v.Field(0)
+		cp.Field(0).Set(copyEvent(parent.Elem(), parent.Type()))
+	}
 
 	return cp.Addr()
 }
+
+var guildIDType = reflect.TypeOf(discord.GuildID(0))
+
+// eventGuildID returns the id of the guild the passed event belongs to, or 0,
+// if the event doesn't belong to a guild.
+// e must be a pointer to an event.
+func eventGuildID(e interface{}) discord.GuildID {
+	v := reflect.ValueOf(e)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return 0
+	}
+
+	v = v.Elem()
+
+	// Guild events, such as GuildCreateEvents, store the guild id in the ID
+	// field.
+	for _, name := range []string{"GuildID", "ID"} {
+		f, ok := v.Type().FieldByName(name)
+		if !ok || f.Type != guildIDType {
+			continue
+		}
+
+		fv, ok := fieldByIndex(v, f.Index)
+		if ok {
+			return fv.Interface().(discord.GuildID)
+		}
+	}
+
+	return 0
+}
+
+// fieldByIndex is the same as reflect.Value.FieldByIndex, but returns false
+// instead of panicking, if it encounters a nil pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v, true
+}
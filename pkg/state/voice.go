@@ -0,0 +1,100 @@
+package state
+
+import (
+	"context"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// VoiceSession contains the information needed to connect to a voice server.
+type VoiceSession struct {
+	// GuildID is the id of the guild of the voice channel.
+	GuildID discord.GuildID
+	// ChannelID is the id of the joined voice channel.
+	ChannelID discord.ChannelID
+	// UserID is the id of the bot.
+	UserID discord.UserID
+	// SessionID is the id of the voice session.
+	SessionID string
+
+	// Token is the token used to authenticate with the voice server.
+	Token string
+	// Endpoint is the host of the voice server.
+	Endpoint string
+}
+
+// JoinVoice joins the voice channel with the passed id, and waits until
+// Discord sent the voice state and the voice server of the connection.
+// The returned VoiceSession can then be used to connect to the voice server.
+// mute and deaf specify whether the bot joins self-muted and self-deafened.
+//
+// JoinVoice blocks until both were received, or the passed context is
+// canceled.
+// Joining voice channels requires the GUILD_VOICE_STATES intent.
+func (s *State) JoinVoice(
+	ctx context.Context, guildID discord.GuildID, channelID discord.ChannelID, mute, deaf bool,
+) (*VoiceSession, error) {
+	me := s.Ready().User.ID
+
+	// Buffer a single update, and drop all further ones, so that the
+	// handler never blocks, even after JoinVoice returned.
+	states := make(chan *gateway.VoiceStateUpdateEvent, 1)
+	servers := make(chan *gateway.VoiceServerUpdateEvent, 1)
+
+	// Use an internal handler, so that middlewares can't filter the updates.
+	rm := s.addInternalHandler(func(gatewayEvent interface{}) {
+		switch e := gatewayEvent.(type) {
+		case *gateway.VoiceStateUpdateEvent:
+			if e.GuildID != guildID || e.UserID != me || e.ChannelID != channelID {
+				return
+			}
+
+			select {
+			case states <- e:
+			default:
+			}
+		case *gateway.VoiceServerUpdateEvent:
+			if e.GuildID != guildID {
+				return
+			}
+
+			select {
+			case servers <- e:
+			default:
+			}
+		}
+	})
+	defer rm()
+
+	err := s.UpdateGuildVoiceState(ctx, guildID, gateway.UpdateVoiceStateData{
+		ChannelID: channelID,
+		SelfMute:  mute,
+		SelfDeaf:  deaf,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vs := &VoiceSession{GuildID: guildID, ChannelID: channelID, UserID: me}
+
+	for vs.SessionID == "" || vs.Endpoint == "" {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case e := <-states:
+			vs.SessionID = e.SessionID
+		case e := <-servers:
+			vs.Token = e.Token
+			vs.Endpoint = e.Endpoint
+		}
+	}
+
+	return vs, nil
+}
+
+// LeaveVoice leaves the voice channel the bot is connected to in the guild
+// with the passed id.
+func (s *State) LeaveVoice(ctx context.Context, guildID discord.GuildID) error {
+	return s.UpdateGuildVoiceState(ctx, guildID, gateway.UpdateVoiceStateData{})
+}
@@ -1,11 +1,16 @@
 package state
 
-import "github.com/diamondburned/arikawa/v2/gateway"
+import (
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
 
 // https://discord.com/developers/docs/topics/gateway#voice-state-update
 type VoiceStateUpdateEvent struct {
 	*gateway.VoiceStateUpdateEvent
 	*Base
+
+	Old *discord.VoiceState
 }
 
 // https://discord.com/developers/docs/topics/gateway#voice-server-update
@@ -0,0 +1,99 @@
+package state
+
+import (
+	"context"
+
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+	"github.com/pkg/errors"
+)
+
+// WarmChannels fetches the channels of the guild with the passed id from the
+// API and stores them in the Cabinet.
+func (s *State) WarmChannels(guildID discord.GuildID) error {
+	channels, err := s.Client.Channels(guildID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range channels {
+		if err := s.Cabinet.ChannelSet(c); err != nil {
+			return errors.Wrap(err, "failed to store channel")
+		}
+	}
+
+	return nil
+}
+
+// WarmRoles fetches the roles of the guild with the passed id from the API
+// and stores them in the Cabinet.
+func (s *State) WarmRoles(guildID discord.GuildID) error {
+	roles, err := s.Client.Roles(guildID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range roles {
+		if err := s.Cabinet.RoleSet(guildID, r); err != nil {
+			return errors.Wrap(err, "failed to store role")
+		}
+	}
+
+	return nil
+}
+
+// WarmBans fetches the bans of the guild with the passed id from the API and
+// caches them.
+// Since the Cabinet has no store for bans, bans are cached by the State
+// itself.
+func (s *State) WarmBans(guildID discord.GuildID) error {
+	bans, err := s.Client.Bans(guildID)
+	if err != nil {
+		return err
+	}
+
+	s.bans.set(guildID, bans)
+	return nil
+}
+
+// WarmGuild fetches the channels and roles of the guild with the passed id
+// from the API, and requests all members of the guild from the gateway,
+// storing them in the Cabinet.
+// It blocks until all members were received, or the passed context is
+// canceled.
+//
+// Requesting members requires the GUILD_MEMBERS intent, and the State must be
+// opened.
+func (s *State) WarmGuild(ctx context.Context, guildID discord.GuildID) error {
+	if err := s.WithContext(ctx).WarmChannels(guildID); err != nil {
+		return errors.Wrap(err, "failed to warm channels")
+	}
+
+	if err := s.WithContext(ctx).WarmRoles(guildID); err != nil {
+		return errors.Wrap(err, "failed to warm roles")
+	}
+
+	// the chunks are stored by the state update
+	_, _, err := s.requestMembers(ctx, gateway.RequestGuildMembersData{
+		GuildID: []discord.GuildID{guildID},
+	})
+
+	return errors.Wrap(err, "failed to request members")
+}
+
+// WarmAll calls WarmGuild for all guilds in the Cabinet, stopping at the
+// first error.
+func (s *State) WarmAll(ctx context.Context) error {
+	guilds, err := s.Cabinet.Guilds()
+	if err != nil {
+		return errors.Wrap(err, "failed to get guilds from cabinet")
+	}
+
+	for _, g := range guilds {
+		if err := s.WarmGuild(ctx, g.ID); err != nil {
+			return errors.Wrapf(err, "failed to warm guild %d", g.ID)
+		}
+	}
+
+	return nil
+}